@@ -0,0 +1,29 @@
+package column
+
+// Bitmap is a compact sequence of bits, columns use it to tell which of their values are valid and which are null
+type Bitmap []uint64
+
+// NewBitmap returns a new Bitmap of n bits all set to v
+func NewBitmap(n int, v bool) Bitmap {
+	b := make(Bitmap, (n+63)/64)
+	if v {
+		for i := 0; i < n; i++ {
+			b.Set(i, true)
+		}
+	}
+	return b
+}
+
+// Get returns the bit at index i
+func (b Bitmap) Get(i int) bool {
+	return b[i/64]&(1<<uint(i%64)) != 0
+}
+
+// Set sets the bit at index i to v
+func (b Bitmap) Set(i int, v bool) {
+	if v {
+		b[i/64] |= 1 << uint(i%64)
+	} else {
+		b[i/64] &^= 1 << uint(i%64)
+	}
+}
@@ -0,0 +1,42 @@
+package column
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBitmap(t *testing.T) {
+	tests := []struct {
+		name  string
+		size  int
+		init  bool
+		flips []int
+	}{
+		{
+			name:  "test_sets_bits_of_empty_bitmap",
+			size:  70,
+			init:  false,
+			flips: []int{0, 63, 64, 69},
+		},
+		{
+			name:  "test_clears_bits_of_full_bitmap",
+			size:  130,
+			init:  true,
+			flips: []int{1, 64, 129},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBitmap(tt.size, tt.init)
+			flipped := map[int]bool{}
+			for _, i := range tt.flips {
+				b.Set(i, !tt.init)
+				flipped[i] = true
+			}
+			for i := 0; i < tt.size; i++ {
+				assert.Equal(t, tt.init != flipped[i], b.Get(i), "bit %d", i)
+			}
+		})
+	}
+}
@@ -0,0 +1,350 @@
+// column package is responsible for holding the typed column model that flows from sources, through pipes, into sinks
+package column
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateLayout is the layout of timestamps that only hold a date, timestamps are otherwise parsed and formatted as RFC3339
+const DateLayout = "2006-01-02"
+
+// Type is the type of the values held by a column
+type Type int
+
+const (
+	Float  Type = iota // values are float64
+	Int                // values are int64
+	String             // values are string
+	Bool               // values are bool
+	Time               // values are time.Time
+)
+
+// String returns the name of the type
+func (t Type) String() string {
+	switch t {
+	case Float:
+		return "float64"
+	case Int:
+		return "int64"
+	case String:
+		return "string"
+	case Bool:
+		return "bool"
+	case Time:
+		return "time.Time"
+	}
+	return fmt.Sprintf("Type(%d)", int(t))
+}
+
+// Zero returns the zero value of the type
+func (t Type) Zero() interface{} {
+	switch t {
+	case Int:
+		return int64(0)
+	case String:
+		return ""
+	case Bool:
+		return false
+	case Time:
+		return time.Time{}
+	}
+	return 0.0
+}
+
+// Parse parses a raw CSV cell into a value of the type
+func (t Type) Parse(raw string) (interface{}, error) {
+	switch t {
+	case Float:
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse row value to float64: %v", raw)
+		}
+		return v, nil
+	case Int:
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse row value to int64: %v", raw)
+		}
+		return v, nil
+	case String:
+		return raw, nil
+	case Bool:
+		switch strings.ToLower(raw) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		return nil, fmt.Errorf("failed to parse row value to bool: %v", raw)
+	case Time:
+		if v, err := time.Parse(time.RFC3339, raw); err == nil {
+			return v, nil
+		}
+		if v, err := time.Parse(DateLayout, raw); err == nil {
+			return v, nil
+		}
+		return nil, fmt.Errorf("failed to parse row value to time.Time: %v", raw)
+	}
+	return nil, fmt.Errorf("cannot parse values of unknown type %v", t)
+}
+
+// Check checks that the given value is of the type
+func (t Type) Check(v interface{}) error {
+	ok := false
+	switch v.(type) {
+	case float64:
+		ok = t == Float
+	case int64:
+		ok = t == Int
+	case string:
+		ok = t == String
+	case bool:
+		ok = t == Bool
+	case time.Time:
+		ok = t == Time
+	}
+	if !ok {
+		return fmt.Errorf("value %v of type %T is not of type %v", v, v, t)
+	}
+	return nil
+}
+
+// Infer returns the most specific type all the given raw CSV cells can be parsed into, trying Int, Float, Bool and Time
+// before falling back to String. Float is assumed when there are no cells
+func Infer(raws []string) Type {
+	if len(raws) == 0 {
+		return Float
+	}
+	for _, t := range []Type{Int, Float, Bool, Time} {
+		fits := true
+		for _, raw := range raws {
+			if _, err := t.Parse(raw); err != nil {
+				fits = false
+				break
+			}
+		}
+		if fits {
+			return t
+		}
+	}
+	return String
+}
+
+// Format formats a value so that parsing it back gives the same value, floats are formatted with the given precision
+func Format(v interface{}, precision int) string {
+	switch val := v.(type) {
+	case float64:
+		return strconv.FormatFloat(val, 'f', precision, 64)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case string:
+		return val
+	case bool:
+		return strconv.FormatBool(val)
+	case time.Time:
+		if val.Equal(val.Truncate(24*time.Hour)) && val.Location() == time.UTC {
+			return val.Format(DateLayout)
+		}
+		return val.Format(time.RFC3339)
+	}
+	return fmt.Sprint(v)
+}
+
+// Column is a named sequence of values of a single type, some of which may be null
+type Column struct {
+	Name   string        // name of the column, e.g the CSV column title
+	Type   Type          // type of all the values of the column
+	Values []interface{} // values of the column, each one of the Go type matching Type or nil when null
+	Valid  Bitmap        // validity of every value, a nil bitmap means all the values are valid
+	Rows   []int         // index of the row every value comes from, e.g once rows were filtered out, nil means in order
+}
+
+// New returns a new instance of a Column
+func New(name string, t Type, values []interface{}) *Column {
+	return &Column{
+		Name:   name,
+		Type:   t,
+		Values: values,
+	}
+}
+
+// Zeros returns a new column of n zero values of the given type
+func Zeros(name string, t Type, n int) *Column {
+	values := make([]interface{}, n)
+	for i := range values {
+		values[i] = t.Zero()
+	}
+	return New(name, t, values)
+}
+
+// FromFloats returns a new Float column holding the given values
+func FromFloats(name string, values []float64) *Column {
+	vs := make([]interface{}, len(values))
+	for i, v := range values {
+		vs[i] = v
+	}
+	return New(name, Float, vs)
+}
+
+// Len returns the number of values of the column
+func (c *Column) Len() int {
+	return len(c.Values)
+}
+
+// Row returns the index of the row the value at index i comes from, which is i unless rows were filtered out
+func (c *Column) Row(i int) int {
+	if c.Rows == nil {
+		return i
+	}
+	return c.Rows[i]
+}
+
+// Take returns a new column holding the values at the given indices, in order, along with the rows they come from
+func (c *Column) Take(indices []int) *Column {
+	t := New(c.Name, c.Type, make([]interface{}, 0, len(indices)))
+	t.Rows = make([]int, 0, len(indices))
+	for _, i := range indices {
+		if c.IsNull(i) {
+			t.Push(nil)
+		} else {
+			t.Push(c.Values[i])
+		}
+		t.Rows = append(t.Rows, c.Row(i))
+	}
+	return t
+}
+
+// IsNull tells whether the value at index i is null
+func (c *Column) IsNull(i int) bool {
+	return c.Valid != nil && !c.Valid.Get(i)
+}
+
+// SetNull marks the value at index i as null
+func (c *Column) SetNull(i int) {
+	if c.Valid == nil {
+		c.Valid = NewBitmap(len(c.Values), true)
+	}
+	c.Valid = c.grow()
+	c.Valid.Set(i, false)
+	c.Values[i] = nil
+}
+
+// NullCount returns the number of null values of the column
+func (c *Column) NullCount() int {
+	n := 0
+	for i := range c.Values {
+		if c.IsNull(i) {
+			n++
+		}
+	}
+	return n
+}
+
+// ValidValues returns the values of the column that are not null
+func (c *Column) ValidValues() []interface{} {
+	if c.Valid == nil {
+		return c.Values
+	}
+	vs := make([]interface{}, 0, len(c.Values))
+	for i, v := range c.Values {
+		if !c.IsNull(i) {
+			vs = append(vs, v)
+		}
+	}
+	return vs
+}
+
+// Push appends a single value to the column, a nil value is appended as null
+func (c *Column) Push(v interface{}) {
+	c.Values = append(c.Values, v)
+	if v == nil {
+		c.SetNull(len(c.Values) - 1)
+	} else if c.Valid != nil {
+		c.Valid = c.grow()
+		c.Valid.Set(len(c.Values)-1, true)
+	}
+}
+
+// grow returns the validity bitmap of the column extended to hold the validity of all of its values
+func (c *Column) grow() Bitmap {
+	if len(c.Valid)*64 >= len(c.Values) {
+		return c.Valid
+	}
+	return append(c.Valid, make(Bitmap, (len(c.Values)+63)/64-len(c.Valid))...)
+}
+
+// Floats returns the values of a numeric column as float64, Int values are converted and null values are NaN
+func (c *Column) Floats() ([]float64, error) {
+	if c.Type != Float && c.Type != Int {
+		return nil, fmt.Errorf("col (%s) of type %v is not numeric", c.Name, c.Type)
+	}
+	vs := make([]float64, len(c.Values))
+	for i, v := range c.Values {
+		switch val := v.(type) {
+		case float64:
+			vs[i] = val
+		case int64:
+			vs[i] = float64(val)
+		default:
+			vs[i] = math.NaN()
+		}
+	}
+	return vs, nil
+}
+
+// As returns the column converted to the given type, only Int columns can be converted to Float
+func (c *Column) As(t Type) (*Column, error) {
+	if c.Type == t {
+		return c, nil
+	}
+	if c.Type == Int && t == Float {
+		vs := make([]interface{}, len(c.Values))
+		for i, v := range c.Values {
+			if v != nil {
+				vs[i] = float64(v.(int64))
+			}
+		}
+		converted := New(c.Name, Float, vs)
+		converted.Valid = c.Valid
+		converted.Rows = c.Rows
+		return converted, nil
+	}
+	return nil, fmt.Errorf("col (%s) of type %v cannot be used as %v", c.Name, c.Type, t)
+}
+
+// Slice returns a new column holding the values of the column in [from, to)
+func (c *Column) Slice(from, to int) *Column {
+	s := New(c.Name, c.Type, make([]interface{}, 0, to-from))
+	for i := from; i < to; i++ {
+		s.Push(c.Values[i])
+	}
+	if c.Rows != nil {
+		s.Rows = append([]int{}, c.Rows[from:to]...)
+	}
+	return s
+}
+
+// Append appends the values of the given column, values keep their own Go type so only columns of the same type
+// should be appended to each other. The rows of the values are kept when either column has filtered rows
+func (c *Column) Append(o *Column) {
+	if c.Rows != nil || o.Rows != nil {
+		rows := make([]int, 0, c.Len()+o.Len())
+		for i := range c.Values {
+			rows = append(rows, c.Row(i))
+		}
+		for i := range o.Values {
+			rows = append(rows, o.Row(i))
+		}
+		defer func() { c.Rows = rows }()
+	}
+	for i, v := range o.Values {
+		if o.IsNull(i) {
+			v = nil
+		}
+		c.Push(v)
+	}
+}
@@ -0,0 +1,267 @@
+package column
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestType_Parse(t *testing.T) {
+	tests := []struct {
+		name        string
+		typ         Type
+		raw         string
+		expected    interface{}
+		expectedErr error
+	}{
+		{
+			name:     "test_parses_float",
+			typ:      Float,
+			raw:      "1.5",
+			expected: 1.5,
+		},
+		{
+			name:     "test_parses_int",
+			typ:      Int,
+			raw:      "42",
+			expected: int64(42),
+		},
+		{
+			name:     "test_parses_string",
+			typ:      String,
+			raw:      "abc",
+			expected: "abc",
+		},
+		{
+			name:     "test_parses_bool",
+			typ:      Bool,
+			raw:      "TRUE",
+			expected: true,
+		},
+		{
+			name:     "test_parses_date",
+			typ:      Time,
+			raw:      "2020-01-02",
+			expected: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "test_parses_timestamp",
+			typ:      Time,
+			raw:      "2020-01-02T03:04:05Z",
+			expected: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+		{
+			name:        "test_returns_err_on_bad_float",
+			typ:         Float,
+			raw:         "a",
+			expectedErr: fmt.Errorf("failed to parse row value to float64: a"),
+		},
+		{
+			name:        "test_returns_err_on_bad_int",
+			typ:         Int,
+			raw:         "1.5",
+			expectedErr: fmt.Errorf("failed to parse row value to int64: 1.5"),
+		},
+		{
+			name:        "test_returns_err_on_bad_bool",
+			typ:         Bool,
+			raw:         "1",
+			expectedErr: fmt.Errorf("failed to parse row value to bool: 1"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := tt.typ.Parse(tt.raw)
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, v)
+			assert.NoError(t, tt.typ.Check(v))
+		})
+	}
+}
+
+func TestInfer(t *testing.T) {
+	tests := []struct {
+		name     string
+		raws     []string
+		expected Type
+	}{
+		{
+			name:     "test_infers_float_on_no_values",
+			raws:     nil,
+			expected: Float,
+		},
+		{
+			name:     "test_infers_int",
+			raws:     []string{"1", "-2"},
+			expected: Int,
+		},
+		{
+			name:     "test_infers_float",
+			raws:     []string{"1", "2.5"},
+			expected: Float,
+		},
+		{
+			name:     "test_infers_bool",
+			raws:     []string{"true", "False"},
+			expected: Bool,
+		},
+		{
+			name:     "test_infers_time",
+			raws:     []string{"2020-01-02", "2020-01-02T03:04:05Z"},
+			expected: Time,
+		},
+		{
+			name:     "test_infers_string",
+			raws:     []string{"1", "a"},
+			expected: String,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Infer(tt.raws))
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{
+			name:     "test_formats_float_with_precision",
+			value:    1.5,
+			expected: "1.500",
+		},
+		{
+			name:     "test_formats_int",
+			value:    int64(42),
+			expected: "42",
+		},
+		{
+			name:     "test_formats_string",
+			value:    "abc",
+			expected: "abc",
+		},
+		{
+			name:     "test_formats_bool",
+			value:    false,
+			expected: "false",
+		},
+		{
+			name:     "test_formats_date",
+			value:    time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+			expected: "2020-01-02",
+		},
+		{
+			name:     "test_formats_timestamp",
+			value:    time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+			expected: "2020-01-02T03:04:05Z",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Format(tt.value, 3))
+		})
+	}
+}
+
+func TestColumn_As(t *testing.T) {
+	tests := []struct {
+		name        string
+		col         *Column
+		typ         Type
+		expected    *Column
+		expectedErr error
+	}{
+		{
+			name:     "test_keeps_column_of_same_type",
+			col:      New("a", String, []interface{}{"x"}),
+			typ:      String,
+			expected: New("a", String, []interface{}{"x"}),
+		},
+		{
+			name:     "test_converts_int_to_float",
+			col:      New("a", Int, []interface{}{int64(1), int64(2)}),
+			typ:      Float,
+			expected: FromFloats("a", []float64{1, 2}),
+		},
+		{
+			name:        "test_returns_err_on_string_to_float",
+			col:         New("a", String, []interface{}{"x"}),
+			typ:         Float,
+			expectedErr: fmt.Errorf("col (a) of type string cannot be used as float64"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := tt.col.As(tt.typ)
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, c)
+		})
+	}
+}
+
+func TestColumn_Nulls(t *testing.T) {
+	c := New("a", Int, nil)
+	c.Push(int64(1))
+	c.Push(nil)
+	c.Push(int64(3))
+	assert.Equal(t, 3, c.Len())
+	assert.False(t, c.IsNull(0))
+	assert.True(t, c.IsNull(1))
+	assert.False(t, c.IsNull(2))
+	assert.Equal(t, 1, c.NullCount())
+	assert.Equal(t, []interface{}{int64(1), int64(3)}, c.ValidValues())
+
+	f, err := c.As(Float)
+	assert.NoError(t, err)
+	assert.True(t, f.IsNull(1))
+	assert.Equal(t, []interface{}{1.0, 3.0}, f.ValidValues())
+
+	s := c.Slice(1, 3)
+	assert.True(t, s.IsNull(0))
+	assert.False(t, s.IsNull(1))
+
+	o := New("a", Int, []interface{}{int64(4)})
+	o.Append(c)
+	assert.Equal(t, 4, o.Len())
+	assert.True(t, o.IsNull(2))
+	assert.Equal(t, 1, o.NullCount())
+}
+
+func TestColumn_Take(t *testing.T) {
+	c := New("a", Int, nil)
+	c.Push(int64(1))
+	c.Push(nil)
+	c.Push(int64(3))
+	assert.Equal(t, 1, c.Row(1))
+
+	k := c.Take([]int{1, 2})
+	assert.Equal(t, 2, k.Len())
+	assert.True(t, k.IsNull(0))
+	assert.Equal(t, []interface{}{int64(3)}, k.ValidValues())
+	assert.Equal(t, []int{1, 2}, k.Rows)
+	assert.Equal(t, 2, k.Row(1))
+
+	kk := k.Take([]int{1})
+	assert.Equal(t, []int{2}, kk.Rows)
+
+	s := k.Slice(1, 2)
+	assert.Equal(t, []int{2}, s.Rows)
+
+	o := New("a", Int, []interface{}{int64(4)})
+	o.Append(k)
+	assert.Equal(t, []int{0, 1, 2}, o.Rows)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/flaviuvadan/pipe-flow/pipe"
 	"github.com/flaviuvadan/pipe-flow/sink"
 	"github.com/flaviuvadan/pipe-flow/source"
+	"github.com/flaviuvadan/pipe-flow/stats"
 	"github.com/flaviuvadan/pipe-flow/structure"
 )
 
@@ -45,33 +46,21 @@ func singleOpPipeExample() {
 	if err := stc.Register(snk); err != nil {
 		panic("failed to add sink to structure")
 	}
-	if d, err := stc.Flow(); err != nil {
+	if r, err := stc.Flow(); err != nil {
 		panic("structure failed to flow")
 	} else {
-		fmt.Printf("Pipe structure done in: %v\n", d)
+		fmt.Printf("Pipe structure done in: %v\n", r.Duration)
 	}
 }
 
 func aggregateOpPipeExample() {
-	pipeA := pipe.NewAggregateOpPipe("column_a_pipe", func(values []float64) (float64, error) {
-		agg := 0.0
-		for _, v := range values {
-			agg += v
-		}
-		return agg, nil
-	})
+	pipeA := pipe.NewAggregateOpPipe("column_a_pipe", stats.Sum)
 	pipeB := pipe.NewSingleOpsPipe("column_b_pipe", []func(v float64) (float64, error){
 		func(v float64) (float64, error) {
 			return v + 1, nil
 		},
 	})
-	pipeC := pipe.NewAggregateOpPipe("column_c_pipe", func(values []float64) (float64, error) {
-		agg := 1.0
-		for _, v := range values {
-			agg *= v
-		}
-		return agg, nil
-	})
+	pipeC := pipe.NewAggregateOpPipe("column_c_pipe", stats.Product)
 	pipes := map[string]*pipe.Pipe{
 		"a": pipeA,
 		"b": pipeB,
@@ -92,10 +81,10 @@ func aggregateOpPipeExample() {
 	if err := stc.Register(snk); err != nil {
 		panic("failed to add sink to structure")
 	}
-	if d, err := stc.Flow(); err != nil {
+	if r, err := stc.Flow(); err != nil {
 		panic("structure failed to flow")
 	} else {
-		fmt.Printf("Pipe structure done in: %v\n", d)
+		fmt.Printf("Pipe structure done in: %v\n", r.Duration)
 	}
 }
 
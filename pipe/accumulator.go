@@ -0,0 +1,72 @@
+package pipe
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flaviuvadan/pipe-flow/column"
+)
+
+// Accumulator is an online aggregate, it receives the values of a column one at a time and can compute its result
+// without holding the whole column in memory, which makes it usable by streaming sources
+type Accumulator interface {
+	Add(v float64) error      // adds a value of the column to the aggregate
+	Result() (float64, error) // returns the aggregate of all the values added so far
+}
+
+// NewAccumulatorPipe returns a new instance of Pipe that aggregates columns with the accumulators created by na, a new
+// accumulator is created for every column the pipe flows
+func NewAccumulatorPipe(ds string, na func() Accumulator) *Pipe {
+	return &Pipe{
+		Description:    ds,
+		inType:         column.Float,
+		outType:        column.Float,
+		newAccumulator: na,
+	}
+}
+
+// Accumulates tells whether the pipe aggregates its columns with accumulators, see NewAccumulatorPipe
+func (p *Pipe) Accumulates() bool {
+	return p.newAccumulator != nil
+}
+
+// Aggregates tells whether the pipe reduces its columns to fewer values than rows, with aggregate ops, accumulators,
+// tumbling and sliding windows or a group by, i.e its outputs are not aligned with the rows of its input
+func (p *Pipe) Aggregates() bool {
+	multi := p.aggregates != nil && p.broadcastOp == nil
+	return p.aggregateOp != nil || multi || p.newAccumulator != nil || p.perWindow() || p.groupBy != nil
+}
+
+// flowThroughAccumulator does the work of the accumulator on the whole input of the pipeline, null values are skipped
+func (p *Pipe) flowThroughAccumulator(ctx context.Context) error {
+	total := p.totalRows()
+	processed := 0
+	p.report(processed, total)
+	for _, col := range p.inputColumns() {
+		in, err := p.filter(ctx, p.input[col], 0)
+		if err != nil {
+			return err
+		}
+		acc := p.newAccumulator()
+		for i, val := range in.Values {
+			if err := ctx.Err(); err != nil {
+				return p.stopped(err)
+			}
+			if in.IsNull(i) {
+				continue
+			}
+			if err := acc.Add(val.(float64)); err != nil {
+				return fmt.Errorf("failed to accumulate val %v on row %v of col (%v), err: %v", val, in.Row(i), col, err)
+			}
+		}
+		val, err := acc.Result()
+		if err != nil {
+			return fmt.Errorf("failed to perform aggregate op on col (%v), err: %v", col, err)
+		}
+		name := p.GetOutputName(col)
+		p.output[name] = column.New(name, column.Float, []interface{}{val})
+		processed += p.input[col].Len()
+		p.report(processed, total)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package pipe
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sumAccumulator is a test accumulator that sums values and fails on negative ones
+type sumAccumulator struct {
+	sum float64
+}
+
+func (a *sumAccumulator) Add(v float64) error {
+	if v < 0 {
+		return fmt.Errorf("negative value")
+	}
+	a.sum += v
+	return nil
+}
+
+func (a *sumAccumulator) Result() (float64, error) {
+	return a.sum, nil
+}
+
+func newSumAccumulator() Accumulator {
+	return &sumAccumulator{}
+}
+
+func TestNewAccumulatorPipe_Flow(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		pipeIn      map[string][]float64
+		pipeOut     map[string][]float64
+		expectedErr error
+	}{
+		{
+			name: "test_accumulates_every_column",
+			pipeIn: map[string][]float64{
+				"a": {1, 2, 3},
+				"b": {4, 5},
+			},
+			pipeOut: map[string][]float64{
+				"a": {6},
+				"b": {9},
+			},
+		},
+		{
+			name: "test_returns_err_on_accumulator_error",
+			pipeIn: map[string][]float64{
+				"a": {1, -2},
+			},
+			expectedErr: fmt.Errorf("failed to accumulate val -2 on row 1 of col (a), err: negative value"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewAccumulatorPipe(tt.name, newSumAccumulator)
+			p.SetInput(tt.pipeIn)
+			err := p.Flow()
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.pipeOut, p.GetOutput())
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package pipe
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flaviuvadan/pipe-flow/column"
+	"github.com/flaviuvadan/pipe-flow/stats"
+)
+
+// Aggregate is a named aggregate op, see NewMultiAggregatePipe and NewBroadcastPipe
+type Aggregate struct {
+	Name string                           // name of the aggregate, e.g sum
+	Op   func([]float64) (float64, error) // aggregates the valid values of a column
+}
+
+// BroadcastOp is a single op that receives, along with every value of a column, the aggregates of the whole column by
+// name, e.g the mean and the standard deviation of the column to compute the z-score of the value
+type BroadcastOp func(v float64, aggs map[string]float64) (float64, error)
+
+// NewMultiAggregatePipe returns a new instance of Pipe that computes several aggregates of its columns at once, e.g
+// sum, mean and count. Every aggregate outputs a column named after the output name of the column and the name of the
+// aggregate, e.g a_sum and a_mean
+func NewMultiAggregatePipe(ds string, aggs ...Aggregate) *Pipe {
+	if aggs == nil {
+		aggs = []Aggregate{}
+	}
+	return &Pipe{
+		Description: ds,
+		inType:      column.Float,
+		outType:     column.Float,
+		aggregates:  aggs,
+	}
+}
+
+// NewBroadcastPipe returns a new instance of Pipe that flows its columns in two passes: the first pass computes the
+// given aggregates of the whole column and the second one applies the broadcast op to every value of the column along
+// with the aggregates, e.g min-max scaling. Null values stay null and are left out of the aggregates
+func NewBroadcastPipe(ds string, aggs []Aggregate, op BroadcastOp) *Pipe {
+	if aggs == nil {
+		aggs = []Aggregate{}
+	}
+	return &Pipe{
+		Description: ds,
+		inType:      column.Float,
+		outType:     column.Float,
+		aggregates:  aggs,
+		broadcastOp: op,
+	}
+}
+
+// NewZScorePipe returns a new instance of Pipe that standardizes the values of its columns, i.e subtracts the mean of
+// the column from every value and divides the result by the standard deviation of the column
+func NewZScorePipe(ds string) *Pipe {
+	return NewBroadcastPipe(ds, []Aggregate{{Name: "mean", Op: stats.Mean}, {Name: "stddev", Op: stats.StdDev}},
+		func(v float64, aggs map[string]float64) (float64, error) {
+			if aggs["stddev"] == 0 {
+				return 0, fmt.Errorf("cannot standardize a column whose values are all equal")
+			}
+			return (v - aggs["mean"]) / aggs["stddev"], nil
+		})
+}
+
+// NewMinMaxScalePipe returns a new instance of Pipe that scales the values of its columns to [0, 1], the minimum of the
+// column becomes 0 and its maximum 1
+func NewMinMaxScalePipe(ds string) *Pipe {
+	return NewBroadcastPipe(ds, []Aggregate{{Name: "min", Op: stats.Min}, {Name: "max", Op: stats.Max}},
+		func(v float64, aggs map[string]float64) (float64, error) {
+			if aggs["max"] == aggs["min"] {
+				return 0, fmt.Errorf("cannot scale a column whose values are all equal")
+			}
+			return (v - aggs["min"]) / (aggs["max"] - aggs["min"]), nil
+		})
+}
+
+// NewPercentOfTotalPipe returns a new instance of Pipe that outputs every value of its columns as a percentage of the
+// sum of the column
+func NewPercentOfTotalPipe(ds string) *Pipe {
+	return NewBroadcastPipe(ds, []Aggregate{{Name: "sum", Op: stats.Sum}},
+		func(v float64, aggs map[string]float64) (float64, error) {
+			if aggs["sum"] == 0 {
+				return 0, fmt.Errorf("cannot take the percentage of a column whose total is 0")
+			}
+			return v / aggs["sum"] * 100, nil
+		})
+}
+
+// aggregateName returns the name of the output column of the given aggregate of the given input column
+func (p *Pipe) aggregateName(col string, agg Aggregate) string {
+	return fmt.Sprintf("%s_%s", p.GetOutputName(col), agg.Name)
+}
+
+// checkAggregates checks that the aggregates of the pipe, if any, have distinct names and ops
+func (p *Pipe) checkAggregates() error {
+	names := map[string]bool{}
+	for _, agg := range p.aggregates {
+		if agg.Op == nil {
+			return p.errorf("has aggregate (%s) without an op", agg.Name)
+		}
+		if names[agg.Name] {
+			return p.errorf("has several aggregates named (%s)", agg.Name)
+		}
+		names[agg.Name] = true
+	}
+	return nil
+}
+
+// computeAggregates computes the aggregates of the pipe over the valid values of the given column
+func (p *Pipe) computeAggregates(ctx context.Context, col string, in *column.Column) (map[string]float64, error) {
+	values := make([]float64, 0, in.Len())
+	for _, v := range in.ValidValues() {
+		values = append(values, v.(float64))
+	}
+	aggs := make(map[string]float64, len(p.aggregates))
+	for i, agg := range p.aggregates {
+		agg := agg
+		val, err := runOp(ctx, p, i, -1, func(context.Context) (float64, error) {
+			return agg.Op(values)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to perform aggregate op (%s) on col (%v), err: %w", agg.Name, col, err)
+		}
+		aggs[agg.Name] = val
+	}
+	return aggs, nil
+}
+
+// flowThroughAggregates does the work of the aggregates, and of the broadcast op if any, on the whole input of the
+// pipeline
+func (p *Pipe) flowThroughAggregates(ctx context.Context) error {
+	total := p.totalRows()
+	processed := 0
+	p.report(processed, total)
+	for _, col := range p.inputColumns() {
+		if err := ctx.Err(); err != nil {
+			return p.stopped(err)
+		}
+		in, err := p.filter(ctx, p.input[col], 0)
+		if err != nil {
+			return err
+		}
+		aggs, err := p.computeAggregates(ctx, col, in)
+		if err != nil {
+			return err
+		}
+		if p.broadcastOp == nil {
+			for _, agg := range p.aggregates {
+				name := p.aggregateName(col, agg)
+				p.output[name] = column.New(name, column.Float, []interface{}{aggs[agg.Name]})
+			}
+			processed += p.input[col].Len()
+			p.report(processed, total)
+			continue
+		}
+		processed += p.input[col].Len() - in.Len()
+		out := column.Zeros(p.GetOutputName(col), column.Float, in.Len())
+		out.Rows = in.Rows
+		// kept holds the rows that are left once the error policy dropped failed ones
+		kept := make([]int, 0, in.Len())
+		for i, val := range in.Values {
+			if err := ctx.Err(); err != nil {
+				return p.stopped(err)
+			}
+			kept = append(kept, i)
+			if in.IsNull(i) {
+				out.SetNull(i)
+				processed++
+				continue
+			}
+			var newVal interface{}
+			v := val.(float64)
+			newVal, err = runOp(ctx, p, 0, in.Row(i), func(context.Context) (interface{}, error) {
+				return p.broadcastOp(v, aggs)
+			})
+			if err != nil {
+				e := &OpError{Row: in.Row(i), Value: val, Err: err}
+				fail := fmt.Errorf("failed to apply broadcast op to val %v on row %v with op msg: %w", val, in.Row(i), err)
+				var keep bool
+				if newVal, keep, err = p.handleError(e, fail); err != nil {
+					return err
+				}
+				if !keep {
+					kept = kept[:len(kept)-1]
+				}
+			}
+			if newVal == nil {
+				out.SetNull(i)
+			} else {
+				out.Values[i] = newVal
+			}
+			processed++
+			if p.reporting != nil && processed%p.reportingEvery == 0 && processed != total {
+				p.report(processed, total)
+			}
+		}
+		if len(kept) < in.Len() {
+			out = out.Take(kept)
+		}
+		p.output[out.Name] = out
+		p.report(processed, total)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package pipe
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flaviuvadan/pipe-flow/column"
+	"github.com/flaviuvadan/pipe-flow/stats"
+)
+
+func TestNewMultiAggregatePipe_Flow(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		aggs        []Aggregate
+		pipeIn      *column.Column
+		pipeOut     map[string][]float64
+		expectedErr error
+	}{
+		{
+			name:    "test_computes_every_aggregate",
+			aggs:    []Aggregate{{Name: "sum", Op: stats.Sum}, {Name: "mean", Op: stats.Mean}, {Name: "count", Op: stats.Count}},
+			pipeIn:  floats("a", 1.0, nil, 2.0, 6.0),
+			pipeOut: map[string][]float64{"a_sum": {9}, "a_mean": {3}, "a_count": {3}},
+		},
+		{
+			name:        "test_returns_err_on_aggregate_err",
+			aggs:        []Aggregate{{Name: "sum", Op: stats.Sum}, {Name: "mean", Op: stats.Mean}},
+			pipeIn:      floats("a", nil),
+			expectedErr: fmt.Errorf("failed to perform aggregate op (mean) on col (a), err: cannot average no values"),
+		},
+		{
+			name:        "test_returns_err_on_duplicate_aggregate",
+			aggs:        []Aggregate{{Name: "sum", Op: stats.Sum}, {Name: "sum", Op: stats.Mean}},
+			pipeIn:      floats("a", 1.0),
+			expectedErr: fmt.Errorf("pipe (test_returns_err_on_duplicate_aggregate) has several aggregates named (sum)"),
+		},
+		{
+			name:        "test_returns_err_on_nil_aggregate_op",
+			aggs:        []Aggregate{{Name: "sum"}},
+			pipeIn:      floats("a", 1.0),
+			expectedErr: fmt.Errorf("pipe (test_returns_err_on_nil_aggregate_op) has aggregate (sum) without an op"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewMultiAggregatePipe(tt.name, tt.aggs...)
+			p.SetTypedInput(map[string]*column.Column{"a": tt.pipeIn})
+			err := p.Flow()
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.pipeOut, p.GetOutput())
+			assert.ElementsMatch(t, []string{"a_sum", "a_mean", "a_count"}, p.GetOutputNames([]string{"a"}))
+			assert.True(t, p.Aggregates())
+		})
+	}
+}
+
+func TestNewBroadcastPipe_Flow(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		pipe        *Pipe
+		pipeIn      *column.Column
+		expected    *column.Column
+		expectedErr error
+	}{
+		{
+			name:     "test_z_score",
+			pipe:     NewZScorePipe("z"),
+			pipeIn:   floats("a", 2.0, 4.0, nil, 6.0),
+			expected: floats("a", -1.224744871391589, 0.0, nil, 1.224744871391589),
+		},
+		{
+			name:     "test_min_max_scale",
+			pipe:     NewMinMaxScalePipe("scale"),
+			pipeIn:   floats("a", 2.0, 4.0, 6.0),
+			expected: floats("a", 0.0, 0.5, 1.0),
+		},
+		{
+			name:     "test_percent_of_total",
+			pipe:     NewPercentOfTotalPipe("percent"),
+			pipeIn:   floats("a", 1.0, 3.0),
+			expected: floats("a", 25.0, 75.0),
+		},
+		{
+			name: "test_broadcasts_custom_aggregates",
+			pipe: NewBroadcastPipe("custom", []Aggregate{{Name: "max", Op: stats.Max}}, func(v float64, aggs map[string]float64) (float64, error) {
+				return aggs["max"] - v, nil
+			}),
+			pipeIn:   floats("a", 1.0, 3.0),
+			expected: floats("a", 2.0, 0.0),
+		},
+		{
+			name:        "test_returns_err_on_broadcast_op_err",
+			pipe:        NewMinMaxScalePipe("scale"),
+			pipeIn:      floats("a", 2.0, 2.0),
+			expectedErr: fmt.Errorf("failed to apply broadcast op to val 2 on row 0 with op msg: cannot scale a column whose values are all equal"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.pipe.SetTypedInput(map[string]*column.Column{"a": tt.pipeIn})
+			err := tt.pipe.Flow()
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, tt.pipe.GetTypedOutput()["a"])
+			assert.False(t, tt.pipe.Aggregates())
+		})
+	}
+}
+
+func TestNewBroadcastPipe_Begin(t *testing.T) {
+	assert.EqualError(t, NewZScorePipe("z").Begin(), "pipe (z) cannot stream through a broadcast op, it needs the aggregates of the whole column")
+	assert.EqualError(t, NewMultiAggregatePipe("multi").Begin(), "pipe (multi) cannot stream through aggregate ops, use accumulators")
+}
@@ -0,0 +1,180 @@
+package pipe
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/flaviuvadan/pipe-flow/column"
+)
+
+// ErrorAction tells what a pipe does with a row whose ops fail
+type ErrorAction int
+
+const (
+	ErrorFail       ErrorAction = iota // fail the whole pipe, this is the default
+	ErrorSkip                          // drop the row, like a filter would
+	ErrorSubstitute                    // output the Substitute value of the policy for the row
+	ErrorDeadLetter                    // drop the row and write it to the DeadLetter CSV file of the policy
+)
+
+// ErrorPolicy tells a pipe how to handle the rows whose ops fail
+type ErrorPolicy struct {
+	Action     ErrorAction // what to do with rows whose ops fail
+	Substitute interface{} // value output for failed rows when Action is ErrorSubstitute, nil means null
+	DeadLetter string      // path of the CSV file failed rows are written to when Action is ErrorDeadLetter
+	MaxErrors  int         // number of failed rows the pipe tolerates before failing anyway, 0 means no limit
+}
+
+// deadLetterHeader is the header of the dead-letter CSV file of a pipe
+var deadLetterHeader = []string{"row", "value", "op", "error"}
+
+// OpError is the error of an op of a pipe on a row
+type OpError struct {
+	Row   int         // index of the row the op failed on
+	Value interface{} // value of the row as it entered the pipe
+	Op    int         // index of the op that failed
+	Err   error       // error the op failed with
+}
+
+// Error returns the message of the error
+func (e *OpError) Error() string {
+	return fmt.Sprintf("failed to apply op to val %v on row %v with op msg: %v", e.Value, e.Row, e.Err)
+}
+
+// Unwrap returns the error the op failed with
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+// Error is an error of a pipe that names the pipe, e.g a pipe that was stopped or whose ops cannot be performed
+type Error struct {
+	Pipe string // Description of the pipe
+	Err  error  // error of the pipe
+}
+
+// Error returns the message of the error
+func (e *Error) Error() string {
+	return fmt.Sprintf("pipe (%s) %v", e.Pipe, e.Err)
+}
+
+// Unwrap returns the error of the pipe
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// errorf returns an Error of the pipe with the given formatted message, which follows the name of the pipe
+func (p *Pipe) errorf(format string, args ...interface{}) error {
+	return &Error{Pipe: p.Description, Err: fmt.Errorf(format, args...)}
+}
+
+// SetErrorPolicy sets what the pipe does with the rows whose ops fail, the single ops, row ops and broadcast ops of a
+// pipe are subject to it. By default the first failed row fails the pipe
+func (p *Pipe) SetErrorPolicy(ep ErrorPolicy) {
+	p.errorPolicy = ep
+}
+
+// GetErrors returns the number of rows whose ops failed and that the error policy of the pipe handled during its last
+// flow, or stream so far
+func (p *Pipe) GetErrors() int {
+	return p.errors
+}
+
+// checkErrorPolicy checks that the error policy of the pipe can be applied
+func (p *Pipe) checkErrorPolicy() error {
+	ep := p.errorPolicy
+	switch ep.Action {
+	case ErrorSubstitute:
+		if ep.Substitute == nil {
+			return nil
+		}
+		types := []column.Type{p.outType}
+		if p.rowOp != nil {
+			types = nil
+			for _, t := range p.rowOutputs {
+				types = append(types, t)
+			}
+		}
+		for _, t := range types {
+			if err := t.Check(ep.Substitute); err != nil {
+				return p.errorf("cannot substitute failed values with %v, err: %v", ep.Substitute, err)
+			}
+		}
+	case ErrorDeadLetter:
+		if ep.DeadLetter == "" {
+			return p.errorf("cannot dead-letter failed rows without a dead-letter file")
+		}
+	}
+	return nil
+}
+
+// handleError applies the error policy of the pipe to the given failed op. It returns the value the row outputs and
+// whether the row is kept, or the error the pipe fails with, i.e fail when the policy does not tolerate the failure
+func (p *Pipe) handleError(e *OpError, fail error) (interface{}, bool, error) {
+	ep := p.errorPolicy
+	if ep.Action == ErrorFail {
+		return nil, false, fail
+	}
+	p.errors++
+	if ep.MaxErrors > 0 && p.errors > ep.MaxErrors {
+		return nil, false, p.errorf("failed on more than %d rows, err: %w", ep.MaxErrors, fail)
+	}
+	switch ep.Action {
+	case ErrorSubstitute:
+		return ep.Substitute, true, nil
+	case ErrorDeadLetter:
+		if err := p.deadLetter(e); err != nil {
+			return nil, false, err
+		}
+	}
+	return nil, false, nil
+}
+
+// deadLetter writes the given failed op to the dead-letter CSV file of the pipe, which is created on the first failure
+// of a flow
+func (p *Pipe) deadLetter(e *OpError) error {
+	if p.deadLetters == nil {
+		f, err := os.Create(p.errorPolicy.DeadLetter)
+		if err != nil {
+			return p.errorf("failed to create dead-letter file, err: %v", err)
+		}
+		p.deadLetterFile = f
+		p.deadLetters = csv.NewWriter(f)
+		if err := p.deadLetters.Write(deadLetterHeader); err != nil {
+			return p.errorf("failed to write dead-letter header, err: %v", err)
+		}
+	}
+	value := ""
+	switch v := e.Value.(type) {
+	case nil:
+	case Record:
+		value = fmt.Sprint(map[string]interface{}(v))
+	default:
+		value = column.Format(v, -1)
+	}
+	r := []string{strconv.Itoa(e.Row), value, strconv.Itoa(e.Op), e.Err.Error()}
+	if err := p.deadLetters.Write(r); err != nil {
+		return p.errorf("failed to write dead-letter record, err: %v", err)
+	}
+	p.deadLetters.Flush()
+	return p.deadLetters.Error()
+}
+
+// closeDeadLetter closes the dead-letter CSV file of the pipe, if any was created
+func (p *Pipe) closeDeadLetter() error {
+	if p.deadLetterFile == nil {
+		return nil
+	}
+	p.deadLetters.Flush()
+	err := p.deadLetters.Error()
+	if cerr := p.deadLetterFile.Close(); err == nil {
+		err = cerr
+	}
+	p.deadLetterFile = nil
+	p.deadLetters = nil
+	if err != nil {
+		return p.errorf("failed to close dead-letter file, err: %v", err)
+	}
+	return nil
+}
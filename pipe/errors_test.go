@@ -0,0 +1,166 @@
+package pipe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flaviuvadan/pipe-flow/column"
+)
+
+// failOnNegative is a test single op that fails on negative values
+func failOnNegative(v float64) (float64, error) {
+	if v < 0 {
+		return 0, fmt.Errorf("negative value")
+	}
+	return v * 2, nil
+}
+
+func TestPipe_SetErrorPolicy(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name           string
+		policy         ErrorPolicy
+		pipeIn         map[string][]float64
+		expected       *column.Column
+		expectedErrors int
+		expectedErr    error
+	}{
+		{
+			name:        "test_fails_by_default",
+			pipeIn:      map[string][]float64{"a": {1, -1, 2}},
+			expectedErr: fmt.Errorf("failed to apply op to val -1 on row 1 with op msg: negative value"),
+		},
+		{
+			name:   "test_skips_failed_rows",
+			policy: ErrorPolicy{Action: ErrorSkip},
+			pipeIn: map[string][]float64{"a": {1, -1, 2}},
+			expected: func() *column.Column {
+				c := column.FromFloats("a", []float64{2, 4})
+				c.Rows = []int{0, 2}
+				return c
+			}(),
+			expectedErrors: 1,
+		},
+		{
+			name:           "test_substitutes_failed_values",
+			policy:         ErrorPolicy{Action: ErrorSubstitute, Substitute: 0.0},
+			pipeIn:         map[string][]float64{"a": {1, -1, 2}},
+			expected:       column.FromFloats("a", []float64{2, 0, 4}),
+			expectedErrors: 1,
+		},
+		{
+			name:           "test_substitutes_nulls",
+			policy:         ErrorPolicy{Action: ErrorSubstitute},
+			pipeIn:         map[string][]float64{"a": {1, -1}},
+			expected:       floats("a", 2.0, nil),
+			expectedErrors: 1,
+		},
+		{
+			name:        "test_fails_above_max_errors",
+			policy:      ErrorPolicy{Action: ErrorSkip, MaxErrors: 1},
+			pipeIn:      map[string][]float64{"a": {-1, 1, -2}},
+			expectedErr: fmt.Errorf("pipe (test_fails_above_max_errors) failed on more than 1 rows, err: failed to apply op to val -2 on row 2 with op msg: negative value"),
+		},
+		{
+			name:        "test_returns_err_on_substitute_of_wrong_type",
+			policy:      ErrorPolicy{Action: ErrorSubstitute, Substitute: "x"},
+			pipeIn:      map[string][]float64{"a": {1}},
+			expectedErr: fmt.Errorf("pipe (test_returns_err_on_substitute_of_wrong_type) cannot substitute failed values with x, err: value x of type string is not of type float64"),
+		},
+		{
+			name:        "test_returns_err_on_dead_letter_without_file",
+			policy:      ErrorPolicy{Action: ErrorDeadLetter},
+			pipeIn:      map[string][]float64{"a": {1}},
+			expectedErr: fmt.Errorf("pipe (test_returns_err_on_dead_letter_without_file) cannot dead-letter failed rows without a dead-letter file"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewSingleOpsPipe(tt.name, []func(float64) (float64, error){failOnNegative})
+			p.SetErrorPolicy(tt.policy)
+			p.SetInput(tt.pipeIn)
+			err := p.Flow()
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, p.GetTypedOutput()["a"])
+			assert.Equal(t, tt.expectedErrors, p.GetErrors())
+		})
+	}
+}
+
+func TestPipe_SetErrorPolicy_DeadLetter(t *testing.T) {
+	fn := "test_dead_letter.csv"
+	p := NewSingleOpsPipe("dead_letter", []func(float64) (float64, error){
+		func(v float64) (float64, error) {
+			return v, nil
+		},
+		failOnNegative,
+	})
+	p.SetErrorPolicy(ErrorPolicy{Action: ErrorDeadLetter, DeadLetter: fn})
+	p.SetInput(map[string][]float64{"a": {1, -1.5, 2, -3}})
+	assert.NoError(t, p.Flow())
+	assert.Equal(t, []interface{}{2.0, 4.0}, p.GetTypedOutput()["a"].Values)
+	assert.Equal(t, 2, p.GetErrors())
+	content, err := ioutil.ReadFile(fn)
+	assert.NoError(t, err)
+	assert.Equal(t, "row,value,op,error\n1,-1.5,1,negative value\n3,-3,1,negative value\n", string(content))
+	if err := os.Remove(fn); err != nil {
+		panic(fmt.Errorf("could not remove %v for tests teardown", fn))
+	}
+}
+
+func TestPipe_SetErrorPolicy_RowPipe(t *testing.T) {
+	p := NewRowPipe("revenue", []string{"price", "qty"}, map[string]column.Type{"revenue": column.Float, "big": column.Bool}, revenue)
+	p.SetErrorPolicy(ErrorPolicy{Action: ErrorSkip})
+	p.SetTypedInput(map[string]*column.Column{
+		// the null price fails the op of the row
+		"price": floats("price", 1.0, nil, 3.0),
+		"qty":   column.FromFloats("qty", []float64{2, 1, 5}),
+	})
+	assert.NoError(t, p.Flow())
+	out := p.GetTypedOutput()
+	assert.Equal(t, []interface{}{2.0, 15.0}, out["revenue"].Values)
+	assert.Equal(t, []int{0, 2}, out["revenue"].Rows)
+	assert.Equal(t, 1, p.GetErrors())
+}
+
+func TestPipe_SetErrorPolicy_FlowChunk(t *testing.T) {
+	p := NewSingleOpsPipe("stream", []func(float64) (float64, error){failOnNegative})
+	p.SetErrorPolicy(ErrorPolicy{Action: ErrorSkip})
+	assert.NoError(t, p.Begin())
+	out, err := p.FlowChunk(context.Background(), fromFloats(map[string][]float64{"a": {-1, 1}}))
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{2.0}, out["a"].Values)
+	assert.Equal(t, []int{1}, out["a"].Rows)
+	_, err = p.End()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, p.GetErrors())
+}
+
+func TestOpError(t *testing.T) {
+	p := NewSingleOpsPipe("op_error", []func(float64) (float64, error){failOnNegative})
+	p.SetInput(map[string][]float64{"a": {-1}})
+	err := p.Flow()
+	var e *OpError
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, &OpError{Row: 0, Value: -1.0, Op: 0, Err: fmt.Errorf("negative value")}, e)
+}
+
+func TestError(t *testing.T) {
+	p := NewRowPipe("error", nil, nil, revenue)
+	p.SetInput(map[string][]float64{"a": {1}})
+	err := p.Flow()
+	var e *Error
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, "error", e.Pipe)
+	assert.EqualError(t, e.Err, "has a row op without input columns")
+}
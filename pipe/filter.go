@@ -0,0 +1,104 @@
+package pipe
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flaviuvadan/pipe-flow/column"
+)
+
+// FilterOp is a context aware predicate over values of a column.Type, rows whose value it rejects are dropped
+type FilterOp func(context.Context, interface{}) (bool, error)
+
+// RowFilter is a context aware predicate over the records of a row pipe, records it rejects are dropped
+type RowFilter func(context.Context, Record) (bool, error)
+
+// NewFilterPipe returns a new instance of Pipe that only keeps the rows whose value keep accepts, values are not
+// modified otherwise
+func NewFilterPipe(ds string, keep func(float64) (bool, error)) *Pipe {
+	p := NewTypedSingleOpsPipe(ds, column.Float, column.Float, []TypedOp{})
+	p.SetFilters(func(_ context.Context, v interface{}) (bool, error) {
+		return keep(v.(float64))
+	})
+	return p
+}
+
+// SetFilters makes the pipe drop the rows whose input value any of the given filters rejects, before the value goes
+// through the ops of the pipe. Null values are never dropped by filters. Outputs of dropped rows are left out and the
+// remaining values keep track of the row they come from, see column.Column.Rows
+func (p *Pipe) SetFilters(fs ...FilterOp) {
+	p.filters = fs
+}
+
+// SetRowFilters makes a row pipe drop the records any of the given filters rejects, before they go through its op
+func (p *Pipe) SetRowFilters(fs ...RowFilter) {
+	p.rowFilters = fs
+}
+
+// GetDropped returns the number of rows the filters of the pipe dropped during its last flow, or stream so far
+func (p *Pipe) GetDropped() int {
+	return p.dropped
+}
+
+// filter returns a column holding the values of c the filters of the pipe keep, c itself when the pipe has no
+// filters. The offset is the index of the first row of c, e.g in a stream
+func (p *Pipe) filter(ctx context.Context, c *column.Column, offset int) (*column.Column, error) {
+	if len(p.filters) == 0 {
+		return c, nil
+	}
+	kept := make([]int, 0, c.Len())
+	for i, v := range c.Values {
+		if err := ctx.Err(); err != nil {
+			return nil, p.stopped(err)
+		}
+		if c.IsNull(i) {
+			kept = append(kept, i)
+			continue
+		}
+		keep, err := p.applyFilters(ctx, v, offset+i)
+		if err != nil {
+			return nil, err
+		}
+		if keep {
+			kept = append(kept, i)
+		} else {
+			p.dropped++
+		}
+	}
+	return c.Take(kept), nil
+}
+
+// applyFilters tells whether all the filters of the pipe keep the given value of the given row
+func (p *Pipe) applyFilters(ctx context.Context, val interface{}, row int) (bool, error) {
+	for i, f := range p.filters {
+		keep, err := f(ctx, val)
+		if err != nil {
+			if ctx.Err() != nil {
+				return false, p.stopped(ctx.Err())
+			}
+			return false, fmt.Errorf("failed to apply filter %d to val %v on row %v with filter msg: %v", i, val, row, err)
+		}
+		if !keep {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// keepRecord tells whether all the row filters of the pipe keep the given record of the given row
+func (p *Pipe) keepRecord(ctx context.Context, rec Record, row int) (bool, error) {
+	for i, f := range p.rowFilters {
+		keep, err := f(ctx, rec)
+		if err != nil {
+			if ctx.Err() != nil {
+				return false, p.stopped(ctx.Err())
+			}
+			return false, fmt.Errorf("failed to apply row filter %d on row %v with filter msg: %v", i, row, err)
+		}
+		if !keep {
+			p.dropped++
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,169 @@
+package pipe
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flaviuvadan/pipe-flow/column"
+)
+
+// positive is a test filter that keeps positive values
+func positive(_ context.Context, v interface{}) (bool, error) {
+	return v.(float64) > 0, nil
+}
+
+func TestPipe_SetFilters(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name            string
+		pipe            *Pipe
+		filters         []FilterOp
+		pipeIn          map[string][]float64
+		pipeOut         map[string][]float64
+		expectedRows    []int
+		expectedDropped int
+		expectedErr     error
+	}{
+		{
+			name: "test_single_ops_drop_rejected_rows",
+			pipe: NewSingleOpsPipe("single", []func(float64) (float64, error){
+				func(v float64) (float64, error) {
+					return v * 2, nil
+				},
+			}),
+			filters:         []FilterOp{positive},
+			pipeIn:          map[string][]float64{"a": {1, -1, 2, -2}},
+			pipeOut:         map[string][]float64{"a": {2, 4}},
+			expectedRows:    []int{0, 2},
+			expectedDropped: 2,
+		},
+		{
+			name:            "test_filter_pipe_keeps_values",
+			pipe:            NewFilterPipe("filter", func(v float64) (bool, error) { return v < 2, nil }),
+			pipeIn:          map[string][]float64{"a": {1, 2, 3}},
+			pipeOut:         map[string][]float64{"a": {1}},
+			expectedRows:    []int{0},
+			expectedDropped: 2,
+		},
+		{
+			name: "test_aggregates_only_kept_rows",
+			pipe: NewAggregateOpPipe("aggregate", func(values []float64) (float64, error) {
+				return float64(len(values)), nil
+			}),
+			filters:         []FilterOp{positive},
+			pipeIn:          map[string][]float64{"a": {1, -1, 2}},
+			pipeOut:         map[string][]float64{"a": {2}},
+			expectedDropped: 1,
+		},
+		{
+			name:            "test_accumulates_only_kept_rows",
+			pipe:            NewAccumulatorPipe("accumulator", newSumAccumulator),
+			filters:         []FilterOp{positive},
+			pipeIn:          map[string][]float64{"a": {1, -1, 2}},
+			pipeOut:         map[string][]float64{"a": {3}},
+			expectedDropped: 1,
+		},
+		{
+			name: "test_returns_err_on_filter_err",
+			pipe: NewSingleOpsPipe("single", []func(float64) (float64, error){
+				func(v float64) (float64, error) {
+					return v, nil
+				},
+			}),
+			filters: []FilterOp{positive, func(_ context.Context, v interface{}) (bool, error) {
+				return false, fmt.Errorf("test error")
+			}},
+			pipeIn:      map[string][]float64{"a": {-1, 1}},
+			expectedErr: fmt.Errorf("failed to apply filter 1 to val 1 on row 1 with filter msg: test error"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.filters != nil {
+				tt.pipe.SetFilters(tt.filters...)
+			}
+			tt.pipe.SetInput(tt.pipeIn)
+			err := tt.pipe.Flow()
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.pipeOut, tt.pipe.GetOutput())
+			assert.Equal(t, tt.expectedRows, tt.pipe.GetTypedOutput()["a"].Rows)
+			assert.Equal(t, tt.expectedDropped, tt.pipe.GetDropped())
+		})
+	}
+}
+
+func TestPipe_SetFilters_KeepsNulls(t *testing.T) {
+	p := NewFilterPipe("filter", func(v float64) (bool, error) { return v > 1, nil })
+	in := column.New("a", column.Float, nil)
+	in.Push(1.0)
+	in.Push(nil)
+	in.Push(2.0)
+	p.SetTypedInput(map[string]*column.Column{"a": in})
+	assert.NoError(t, p.Flow())
+	out := p.GetTypedOutput()["a"]
+	assert.Equal(t, []int{1, 2}, out.Rows)
+	assert.True(t, out.IsNull(0))
+	assert.Equal(t, []interface{}{2.0}, out.ValidValues())
+	assert.Equal(t, 1, p.GetDropped())
+}
+
+func TestPipe_SetRowFilters(t *testing.T) {
+	p := NewRowPipe("revenue", []string{"price", "qty"}, map[string]column.Type{"revenue": column.Float, "big": column.Bool}, revenue)
+	p.SetRowFilters(func(_ context.Context, r Record) (bool, error) {
+		qty, err := r.Float("qty")
+		return qty > 1, err
+	})
+	p.SetTypedInput(map[string]*column.Column{
+		"price": column.FromFloats("price", []float64{1, 2, 3}),
+		"qty":   column.FromFloats("qty", []float64{2, 1, 5}),
+	})
+	assert.NoError(t, p.Flow())
+	out := p.GetTypedOutput()
+	assert.Equal(t, []interface{}{2.0, 15.0}, out["revenue"].Values)
+	assert.Equal(t, []int{0, 2}, out["revenue"].Rows)
+	assert.Equal(t, []int{0, 2}, out["big"].Rows)
+	assert.Equal(t, 1, p.GetDropped())
+}
+
+func TestPipe_SetFilters_FlowChunk(t *testing.T) {
+	p := NewFilterPipe("filter", func(v float64) (bool, error) { return v > 1, nil })
+	assert.NoError(t, p.Begin())
+	chunks := []map[string][]float64{
+		{"a": {1, 2}},
+		{"a": {3, 0}},
+	}
+	expected := []map[string][]float64{
+		{"a": {2}},
+		{"a": {3}},
+	}
+	expectedRows := [][]int{{1}, {0}}
+	for i, c := range chunks {
+		out, err := p.FlowChunk(context.Background(), fromFloats(c))
+		assert.NoError(t, err)
+		assert.Equal(t, expected[i], toFloats(out))
+		assert.Equal(t, expectedRows[i], out["a"].Rows)
+	}
+	assert.Equal(t, 2, p.GetDropped())
+}
+
+func TestTyped_Where(t *testing.T) {
+	p := NewMap[int64, int64]("double", func(v int64) (int64, error) {
+		return v * 2, nil
+	}).Where(func(v int64) (bool, error) {
+		return v%2 == 0, nil
+	}).Pipe()
+	p.SetTypedInput(map[string]*column.Column{
+		"a": column.New("a", column.Int, []interface{}{int64(1), int64(2), int64(4)}),
+	})
+	assert.NoError(t, p.Flow())
+	out := p.GetTypedOutput()["a"]
+	assert.Equal(t, []interface{}{int64(4), int64(8)}, out.Values)
+	assert.Equal(t, []int{1, 2}, out.Rows)
+}
@@ -0,0 +1,166 @@
+package pipe
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/flaviuvadan/pipe-flow/column"
+)
+
+// Value is the set of Go types the values of columns can have, see column.Type
+type Value interface {
+	float64 | int64 | string | bool | time.Time
+}
+
+// Typed is a generics based pipe whose values flow in as In and out as Out. It builds the Pipe that is registered with
+// sources and sinks, see Pipe, while keeping the ops that are given to it type safe. It is the Pipe[In, Out] of the
+// pipe package under another name: Go does not let a generic type share the name of the Pipe type that sources, sinks
+// and existing code already use, and renaming Pipe would break all of them
+type Typed[In, Out Value] struct {
+	Description string           // a Description/name of the pipeline, used for monitoring
+	singleOps   []TypedOp        // the single ops of the pipe, chained from In to Out
+	aggregateOp TypedAggregateOp // the aggregate op of the pipe, from []In to Out
+	filters     []FilterOp       // the filters of the pipe, over values of type In
+	pipe        *Pipe            // the pipe that was built from the ops, see Pipe
+}
+
+// NewSingleOps returns a new Typed pipe whose single ops modify values of the same type
+func NewSingleOps[T Value](ds string, ops []func(T) (T, error)) *Typed[T, T] {
+	t := &Typed[T, T]{Description: ds}
+	for _, op := range ops {
+		t.singleOps = append(t.singleOps, typedOp(op))
+	}
+	return t
+}
+
+// NewMap returns a new Typed pipe with a single op that changes the type of values, e.g parses strings into float64
+func NewMap[In, Out Value](ds string, op func(In) (Out, error)) *Typed[In, Out] {
+	return &Typed[In, Out]{
+		Description: ds,
+		singleOps:   []TypedOp{typedOp(op)},
+	}
+}
+
+// Then returns a new Typed pipe that applies op to the values returned by the single ops of t
+func Then[In, Mid, Out Value](t *Typed[In, Mid], op func(Mid) (Out, error)) *Typed[In, Out] {
+	ops := make([]TypedOp, 0, len(t.singleOps)+1)
+	ops = append(ops, t.singleOps...)
+	return &Typed[In, Out]{
+		Description: t.Description,
+		singleOps:   append(ops, typedOp(op)),
+		filters:     t.filters,
+	}
+}
+
+// Where makes the pipe drop the rows whose value of type In keep rejects, see Pipe.SetFilters. It returns t so that
+// filters can be chained
+func (t *Typed[In, Out]) Where(keep func(In) (bool, error)) *Typed[In, Out] {
+	t.filters = append(t.filters, func(_ context.Context, v interface{}) (bool, error) {
+		in, ok := v.(In)
+		if !ok {
+			return false, fmt.Errorf("value %v of type %T is not of type %v", v, v, TypeOf[In]())
+		}
+		return keep(in)
+	})
+	return t
+}
+
+// NewAggregate returns a new Typed pipe with an aggregate op that summarizes values of type In into a value of type Out
+func NewAggregate[In, Out Value](ds string, op func([]In) (Out, error)) *Typed[In, Out] {
+	t := &Typed[In, Out]{Description: ds}
+	if op != nil {
+		t.aggregateOp = func(_ context.Context, values []interface{}) (interface{}, error) {
+			vs := make([]In, len(values))
+			for i, v := range values {
+				vs[i] = v.(In)
+			}
+			return op(vs)
+		}
+	}
+	return t
+}
+
+// Pipe returns the Pipe built from the ops of t, the same Pipe is returned on every call
+func (t *Typed[In, Out]) Pipe() *Pipe {
+	if t.pipe != nil {
+		return t.pipe
+	}
+	in, out := TypeOf[In](), TypeOf[Out]()
+	if t.aggregateOp != nil {
+		t.pipe = NewTypedAggregateOpPipe(t.Description, in, out, t.aggregateOp)
+	} else {
+		ops := t.singleOps
+		if ops == nil && len(t.filters) > 0 {
+			// a pipe that only filters lets the values it keeps through
+			ops = []TypedOp{}
+		}
+		t.pipe = NewTypedSingleOpsPipe(t.Description, in, out, ops)
+	}
+	t.pipe.SetFilters(t.filters...)
+	return t.pipe
+}
+
+// SetInput sets the inputs to the pipe, mostly useful when the pipe flows outside of a structure
+func (t *Typed[In, Out]) SetInput(in map[string][]In) {
+	cols := make(map[string]*column.Column, len(in))
+	for k, vs := range in {
+		values := make([]interface{}, len(vs))
+		for i, v := range vs {
+			values[i] = v
+		}
+		cols[k] = column.New(k, TypeOf[In](), values)
+	}
+	t.Pipe().SetTypedInput(cols)
+}
+
+// GetOutput returns the valid output values of the pipe with values of type Out, nulls are left out, see
+// Pipe.GetTypedOutput for their rows
+func (t *Typed[In, Out]) GetOutput() map[string][]Out {
+	cols := t.Pipe().GetTypedOutput()
+	if cols == nil {
+		return nil
+	}
+	out := make(map[string][]Out, len(cols))
+	for k, c := range cols {
+		valid := c.ValidValues()
+		vs := make([]Out, len(valid))
+		for i, v := range valid {
+			vs[i] = v.(Out)
+		}
+		out[k] = vs
+	}
+	return out
+}
+
+// Flow flows the input of the pipe through its ops, see Pipe.Flow
+func (t *Typed[In, Out]) Flow() error {
+	return t.Pipe().Flow()
+}
+
+// TypeOf returns the column.Type of values of the Go type T
+func TypeOf[T Value]() column.Type {
+	var zero T
+	switch any(zero).(type) {
+	case int64:
+		return column.Int
+	case string:
+		return column.String
+	case bool:
+		return column.Bool
+	case time.Time:
+		return column.Time
+	}
+	return column.Float
+}
+
+// typedOp converts a generic single op into a TypedOp
+func typedOp[In, Out Value](op func(In) (Out, error)) TypedOp {
+	return func(_ context.Context, v interface{}) (interface{}, error) {
+		in, ok := v.(In)
+		if !ok {
+			return nil, fmt.Errorf("value %v of type %T is not of type %v", v, v, TypeOf[In]())
+		}
+		return op(in)
+	}
+}
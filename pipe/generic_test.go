@@ -0,0 +1,105 @@
+package pipe
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flaviuvadan/pipe-flow/column"
+)
+
+func TestNewMap_Flow(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		pipeIn      map[string][]string
+		pipeOut     map[string][]float64
+		expectedErr error
+	}{
+		{
+			name:    "test_parses_and_doubles_values",
+			pipeIn:  map[string][]string{"a": {"1.5", "2"}},
+			pipeOut: map[string][]float64{"a": {3, 4}},
+		},
+		{
+			name:        "test_returns_err_on_op_err",
+			pipeIn:      map[string][]string{"a": {"x"}},
+			expectedErr: fmt.Errorf("failed to apply op to val x on row 0 with op msg: strconv.ParseFloat: parsing \"x\": invalid syntax"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parse := NewMap(tt.name, func(v string) (float64, error) {
+				return strconv.ParseFloat(v, 64)
+			})
+			p := Then(parse, func(v float64) (float64, error) {
+				return v * 2, nil
+			})
+			p.SetInput(tt.pipeIn)
+			err := p.Flow()
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.pipeOut, p.GetOutput())
+		})
+	}
+}
+
+func TestNewAggregate_Flow(t *testing.T) {
+	t.Parallel()
+	p := NewAggregate("latest", func(values []time.Time) (time.Time, error) {
+		latest := values[0]
+		for _, v := range values[1:] {
+			if v.After(latest) {
+				latest = v
+			}
+		}
+		return latest, nil
+	})
+	first := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	last := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	p.SetInput(map[string][]time.Time{"a": {first, last}})
+	assert.NoError(t, p.Flow())
+	assert.Equal(t, map[string][]time.Time{"a": {last}}, p.GetOutput())
+	assert.Equal(t, column.Time, p.Pipe().GetTypedOutput()["a"].Type)
+}
+
+func TestNewSingleOps_Pipe(t *testing.T) {
+	t.Parallel()
+	p := NewSingleOps("ints", []func(int64) (int64, error){
+		func(v int64) (int64, error) {
+			return v + 1, nil
+		},
+	})
+	assert.Same(t, p.Pipe(), p.Pipe())
+	p.SetInput(map[string][]int64{"a": {1, 2}})
+	assert.NoError(t, p.Flow())
+	assert.Equal(t, map[string][]int64{"a": {2, 3}}, p.GetOutput())
+}
+
+func TestNewSingleOps_GetOutput_SkipsNulls(t *testing.T) {
+	t.Parallel()
+	p := NewSingleOps("floats", []func(float64) (float64, error){
+		func(v float64) (float64, error) {
+			return v * 2, nil
+		},
+	})
+	p.Pipe().SetTypedInput(map[string]*column.Column{"a": floats("a", 1.0, nil, 3.0)})
+	assert.NoError(t, p.Flow())
+	assert.True(t, p.Pipe().GetTypedOutput()["a"].IsNull(1))
+	assert.Equal(t, map[string][]float64{"a": {2, 6}}, p.GetOutput())
+}
+
+func TestTypeOf(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, column.Float, TypeOf[float64]())
+	assert.Equal(t, column.Int, TypeOf[int64]())
+	assert.Equal(t, column.String, TypeOf[string]())
+	assert.Equal(t, column.Bool, TypeOf[bool]())
+	assert.Equal(t, column.Time, TypeOf[time.Time]())
+}
@@ -0,0 +1,170 @@
+package pipe
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flaviuvadan/pipe-flow/column"
+)
+
+// groupBy holds the configuration of a group by pipe
+type groupBy struct {
+	key   string                           // name of the column whose distinct values form the groups
+	value string                           // name of the column whose values are aggregated per group
+	op    func([]float64) (float64, error) // aggregates the valid values of a group
+}
+
+// group holds the key of a group along with the rows of the value column that are part of it
+type group struct {
+	key    interface{} // key of the group, nil for the group of null keys
+	values []float64   // valid values of the group
+}
+
+// NewGroupByPipe returns a new instance of Pipe that groups the rows of the value column by the distinct values of the
+// key column and applies the aggregate op to the values of every group, e.g totals per region. It outputs the key
+// column, holding every distinct key in the order it first appears in, and the value column, holding the aggregate of
+// every key. Rows of null keys form a group of their own and null values are left out of the aggregates. Group by
+// pipes are bound to a source like row pipes, with source.WithRowPipes, and SetOutputName renames the aggregate column,
+// which must not be named like the key column
+func NewGroupByPipe(ds, key, value string, op func([]float64) (float64, error)) *Pipe {
+	return &Pipe{
+		Description: ds,
+		inType:      column.Float,
+		outType:     column.Float,
+		rowInputs:   []string{key, value},
+		groupBy:     &groupBy{key: key, value: value, op: op},
+	}
+}
+
+// checkGroupBy checks that the group by of the pipe, if any, can be flowed and names its aggregate column unlike its key
+func (p *Pipe) checkGroupBy() error {
+	g := p.groupBy
+	if g == nil {
+		return nil
+	}
+	if g.op == nil {
+		return p.errorf("has a group by without an op")
+	}
+	if p.GetOutputName(g.value) == g.key {
+		return p.errorf("outputs its aggregate column as its key column (%s), set a distinct output name", g.key)
+	}
+	return nil
+}
+
+// flowThroughGroupBy does the work of the group by on the whole input of the pipeline
+func (p *Pipe) flowThroughGroupBy(ctx context.Context) error {
+	g := p.groupBy
+	key, ok := p.input[g.key]
+	if !ok {
+		return p.errorf("is missing input column (%s)", g.key)
+	}
+	value, ok := p.input[g.value]
+	if !ok {
+		return p.errorf("is missing input column (%s)", g.value)
+	}
+	value, err := value.As(column.Float)
+	if err != nil {
+		return p.errorf("cannot flow input, err: %v", err)
+	}
+	total := p.rowCount(p.input)
+	p.report(0, total)
+
+	groups, err := p.group(ctx, key, value)
+	if err != nil {
+		return err
+	}
+	keys := column.New(g.key, key.Type, make([]interface{}, 0, len(groups)))
+	name := p.GetOutputName(g.value)
+	aggregates := column.New(name, column.Float, make([]interface{}, 0, len(groups)))
+	for _, gr := range groups {
+		if err := ctx.Err(); err != nil {
+			return p.stopped(err)
+		}
+		keys.Push(gr.key)
+		if len(gr.values) == 0 {
+			aggregates.Push(nil)
+			continue
+		}
+		values := gr.values
+		val, err := runOp(ctx, p, 0, -1, func(context.Context) (float64, error) {
+			return g.op(values)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to perform aggregate op on group (%v) of col (%v), err: %w", printGroup(gr.key), g.value, err)
+		}
+		aggregates.Push(val)
+	}
+	p.output[keys.Name] = keys
+	p.output[aggregates.Name] = aggregates
+	p.report(total, total)
+	return nil
+}
+
+// group splits the valid values of the value column into the groups of the key column, in the order every key first
+// appears in. Filters of the pipe apply to the values and row filters to the records of both columns
+func (p *Pipe) group(ctx context.Context, key, value *column.Column) ([]*group, error) {
+	var groups []*group
+	index := map[string]*group{}
+	var nulls *group
+	rows := key.Len()
+	if value.Len() > rows {
+		rows = value.Len()
+	}
+	for i := 0; i < rows; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, p.stopped(err)
+		}
+		rec := Record{p.groupBy.key: nil, p.groupBy.value: nil}
+		if i < key.Len() && !key.IsNull(i) {
+			rec[p.groupBy.key] = key.Values[i]
+		}
+		if i < value.Len() && !value.IsNull(i) {
+			rec[p.groupBy.value] = value.Values[i]
+		}
+		keep, err := p.keepRecord(ctx, rec, i)
+		if err != nil {
+			return nil, err
+		}
+		if !keep {
+			continue
+		}
+		val := rec[p.groupBy.value]
+		if val != nil && len(p.filters) > 0 {
+			keep, err := p.applyFilters(ctx, val, i)
+			if err != nil {
+				return nil, err
+			}
+			if !keep {
+				p.dropped++
+				continue
+			}
+		}
+		var gr *group
+		if k := rec[p.groupBy.key]; k == nil {
+			if nulls == nil {
+				nulls = &group{}
+				groups = append(groups, nulls)
+			}
+			gr = nulls
+		} else {
+			id := column.Format(k, -1)
+			if gr = index[id]; gr == nil {
+				gr = &group{key: k}
+				index[id] = gr
+				groups = append(groups, gr)
+			}
+		}
+		if val != nil {
+			gr.values = append(gr.values, val.(float64))
+		}
+	}
+	return groups, nil
+}
+
+// printGroup formats the key of a group for error messages
+func printGroup(key interface{}) string {
+	if key == nil {
+		return "null"
+	}
+	return column.Format(key, -1)
+}
@@ -0,0 +1,118 @@
+package pipe
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flaviuvadan/pipe-flow/column"
+	"github.com/flaviuvadan/pipe-flow/stats"
+)
+
+func TestNewGroupByPipe_Flow(t *testing.T) {
+	t.Parallel()
+	regions := func(values ...interface{}) *column.Column {
+		c := column.New("region", column.String, nil)
+		for _, v := range values {
+			c.Push(v)
+		}
+		return c
+	}
+	tests := []struct {
+		name         string
+		op           func([]float64) (float64, error)
+		key          *column.Column
+		value        *column.Column
+		expectedKeys *column.Column
+		expected     *column.Column
+		expectedErr  error
+	}{
+		{
+			name:         "test_aggregates_every_key_in_order_of_appearance",
+			op:           stats.Sum,
+			key:          regions("north", "south", "north", "east"),
+			value:        floats("amount", 1.0, 2.0, 3.0, 4.0),
+			expectedKeys: regions("north", "south", "east"),
+			expected:     floats("amount", 4.0, 2.0, 4.0),
+		},
+		{
+			name:         "test_groups_null_keys_and_skips_null_values",
+			op:           stats.Mean,
+			key:          regions("north", nil, "north", "east", nil),
+			value:        floats("amount", 1.0, 2.0, 3.0, nil, 6.0),
+			expectedKeys: regions("north", nil, "east"),
+			expected:     floats("amount", 2.0, 4.0, nil),
+		},
+		{
+			name: "test_returns_err_on_op_err",
+			op: func(values []float64) (float64, error) {
+				return 0, fmt.Errorf("test error")
+			},
+			key:         regions("north"),
+			value:       floats("amount", 1.0),
+			expectedErr: fmt.Errorf("failed to perform aggregate op on group (north) of col (amount), err: test error"),
+		},
+		{
+			name:        "test_returns_err_on_nil_op",
+			key:         regions("north"),
+			value:       floats("amount", 1.0),
+			expectedErr: fmt.Errorf("pipe (test_returns_err_on_nil_op) has a group by without an op"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewGroupByPipe(tt.name, "region", "amount", tt.op)
+			p.SetTypedInput(map[string]*column.Column{"region": tt.key, "amount": tt.value})
+			err := p.Flow()
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedKeys, p.GetTypedOutput()["region"])
+			assert.Equal(t, tt.expected, p.GetTypedOutput()["amount"])
+		})
+	}
+}
+
+func TestNewGroupByPipe_Filters(t *testing.T) {
+	p := NewGroupByPipe("totals", "region", "amount", stats.Sum)
+	p.SetOutputName("total")
+	p.SetFilters(positive)
+	p.SetRowFilters(func(_ context.Context, r Record) (bool, error) {
+		return r["region"] != "east", nil
+	})
+	p.SetTypedInput(map[string]*column.Column{
+		"region": column.New("region", column.String, []interface{}{"north", "east", "north", "south"}),
+		"amount": column.New("amount", column.Int, []interface{}{int64(1), int64(2), int64(-3), int64(4)}),
+	})
+	assert.NoError(t, p.Flow())
+	assert.Equal(t, []string{"region", "total"}, p.GetOutputNames(nil))
+	assert.Equal(t, []interface{}{"north", "south"}, p.GetTypedOutput()["region"].Values)
+	assert.Equal(t, []interface{}{1.0, 4.0}, p.GetTypedOutput()["total"].Values)
+	assert.Equal(t, 2, p.GetDropped())
+	assert.True(t, p.Aggregates())
+	assert.EqualError(t, p.Begin(), "pipe (totals) cannot stream through a group by")
+}
+
+func TestNewGroupByPipe_Flow_KeyAsValue(t *testing.T) {
+	count := func(values []float64) (float64, error) {
+		return float64(len(values)), nil
+	}
+	p := NewGroupByPipe("counts", "a", "a", count)
+	p.SetTypedInput(map[string]*column.Column{"a": floats("a", 2.0, 1.0, 2.0)})
+	assert.EqualError(t, p.Flow(), "pipe (counts) outputs its aggregate column as its key column (a), set a distinct output name")
+	p = NewGroupByPipe("totals", "region", "amount", stats.Sum)
+	p.SetOutputName("region")
+	p.SetTypedInput(map[string]*column.Column{"region": floats("region", 1.0), "amount": floats("amount", 1.0)})
+	assert.EqualError(t, p.Flow(), "pipe (totals) outputs its aggregate column as its key column (region), set a distinct output name")
+
+	p = NewGroupByPipe("counts", "a", "a", count)
+	p.SetOutputName("a_count")
+	p.SetTypedInput(map[string]*column.Column{"a": floats("a", 2.0, 1.0, 2.0)})
+	assert.NoError(t, p.Flow())
+	assert.Equal(t, []interface{}{2.0, 1.0}, p.GetTypedOutput()["a"].Values)
+	assert.Equal(t, []interface{}{2.0, 1.0}, p.GetTypedOutput()["a_count"].Values)
+}
@@ -2,34 +2,139 @@
 package pipe
 
 import (
+	"context"
+	"encoding/csv"
+	"errors"
 	"fmt"
+	"os"
+	"sort"
+	"sync"
 	"time"
+
+	"github.com/flaviuvadan/pipe-flow/column"
+	"github.com/flaviuvadan/pipe-flow/progress"
 )
 
+// DefaultReportEvery is the number of rows between progress reports when no interval is specified
+const DefaultReportEvery = 1000
+
 // Pipe struct represents a pipeline through which data flows
 type Pipe struct {
-	Description string                           // a Description/name of the pipeline, used for monitoring
-	input       map[string][]float64             // data that the pipe will apply the op to
-	singleOps   []func(float64) (float64, error) // the singleOp that will be applied to independent input data points
-	aggregateOp func([]float64) (float64, error) // the aggregateOp that will be applied to the whole CSV column
-	output      map[string][]float64             // the output after applying the singleOp to the input
-	start       time.Time                        // start time of the pipeline
-	end         time.Time                        // end time of the pipeline
+	Description    string                    // a Description/name of the pipeline, used for monitoring
+	inType         column.Type               // type of the values the ops of the pipe take
+	outType        column.Type               // type of the values the ops of the pipe return
+	input          map[string]*column.Column // data that the pipe will apply the op to
+	outputName     string                    // name of the output column, the name of the input column when empty
+	singleOps      []TypedOp                 // the singleOp that will be applied to independent input data points
+	aggregateOp    TypedAggregateOp          // the aggregateOp that will be applied to the whole CSV column
+	rowOp          RowOp                     // the op that will be applied to the records of several input columns
+	rowInputs      []string                  // names of the input columns of the rowOp
+	rowOutputs     map[string]column.Type    // names and types of the output columns of the rowOp
+	upstream       []*Pipe                   // pipes whose output is the input of this pipe, see SetUpstream
+	filters        []FilterOp                // filters that drop rows before the singleOps or aggregateOp see them
+	rowFilters     []RowFilter               // filters that drop records before the rowOp sees them
+	window         *window                   // the window the columns flow through, see NewRollingPipe
+	groupBy        *groupBy                  // the key and value columns of a group by, see NewGroupByPipe
+	aggregates     []Aggregate               // the named aggregates computed at once, see NewMultiAggregatePipe
+	broadcastOp    BroadcastOp               // the op the aggregates are broadcast into, see NewBroadcastPipe
+	dropped        int                       // number of rows the filters dropped during the last flow
+	errorPolicy    ErrorPolicy               // what to do with the rows whose ops fail, see SetErrorPolicy
+	errors         int                       // number of failed rows the errorPolicy handled during the last flow
+	opRetry        RetryPolicy               // how to retry the ops that fail, see SetRetryPolicy
+	flowRetry      RetryPolicy               // how to retry the flows that fail, see SetFlowRetryPolicy
+	opRetries      int                       // number of times ops were retried during the last flow
+	flowRetries    int                       // number of times the last flow was retried
+	opTimeout      time.Duration             // bound of every op call, see SetOpTimeout
+	running        map[int]<-chan struct{}   // op calls, by op index, that exceeded the opTimeout and did not return yet
+	runningMu      sync.Mutex                // guards running, aggregate ops are called from their own goroutine
+	timeout        time.Duration             // bound of every flow, or stream, see SetTimeout
+	deadline       time.Time                 // when the current flow, or stream, exceeds the timeout, zero without one
+	rowsIn         int                       // number of rows that entered the pipe during the last flow
+	rowsOut        int                       // number of rows that left the pipe during the last flow
+	nulls          int                       // number of null values the pipe output during the last flow
+	deadLetters    *csv.Writer               // CSV writer of the dead-letter file of the errorPolicy, nil until a row fails
+	deadLetterFile *os.File                  // dead-letter file of the errorPolicy
+	output         map[string]*column.Column // the output after applying the singleOp to the input
+	start          time.Time                 // start time of the pipeline
+	end            time.Time                 // end time of the pipeline
+	reporter       progress.Reporter         // receives the progress of the pipe as rows are processed, may be nil
+	reportEvery    int                       // number of rows between progress reports
+	reporting      progress.Reporter         // reporter of the current flow, the reporter of the pipe or of its context
+	reportingEvery int                       // number of rows between progress reports of the current flow
+
+	newAccumulator func() Accumulator      // creates the online accumulators of the pipe, one per input column
+	accumulators   map[string]Accumulator  // accumulators of the columns that are being streamed
+	streamed       int                     // number of rows that were streamed through the pipe so far
+	windowStates   map[string]*windowState // windows of the columns that are being streamed
 }
 
+// SingleOpContext is a single op that can observe the context of the flow it is part of
+type SingleOpContext func(context.Context, float64) (float64, error)
+
+// AggregateOpContext is an aggregate op that can observe the context of the flow it is part of
+type AggregateOpContext func(context.Context, []float64) (float64, error)
+
+// TypedOp is a context aware single op over values of a column.Type, e.g it receives an int64 from an Int column
+type TypedOp func(context.Context, interface{}) (interface{}, error)
+
+// TypedAggregateOp is a context aware aggregate op over the values of a column.Type
+type TypedAggregateOp func(context.Context, []interface{}) (interface{}, error)
+
 // NewSingleOpsPipe returns a new instance of Pipe that uses single ops to modify values that flow through
 func NewSingleOpsPipe(ds string, so []func(float64) (float64, error)) *Pipe {
+	return NewSingleOps(ds, so).Pipe()
+}
+
+// NewAggregateOpPipe returns a new instance of Pipe with an aggregate function
+func NewAggregateOpPipe(ds string, ao func([]float64) (float64, error)) *Pipe {
+	return NewAggregate(ds, ao).Pipe()
+}
+
+// NewSingleOpsPipeContext returns a new instance of Pipe that uses context aware single ops to modify values
+func NewSingleOpsPipeContext(ds string, so []SingleOpContext) *Pipe {
+	var ops []TypedOp
+	for _, op := range so {
+		op := op
+		ops = append(ops, func(ctx context.Context, v interface{}) (interface{}, error) {
+			return op(ctx, v.(float64))
+		})
+	}
+	return NewTypedSingleOpsPipe(ds, column.Float, column.Float, ops)
+}
+
+// NewAggregateOpPipeContext returns a new instance of Pipe with a context aware aggregate function
+func NewAggregateOpPipeContext(ds string, ao AggregateOpContext) *Pipe {
+	if ao == nil {
+		return NewTypedAggregateOpPipe(ds, column.Float, column.Float, nil)
+	}
+	return NewTypedAggregateOpPipe(ds, column.Float, column.Float, func(ctx context.Context, values []interface{}) (interface{}, error) {
+		vs := make([]float64, len(values))
+		for i, v := range values {
+			vs[i] = v.(float64)
+		}
+		return ao(ctx, vs)
+	})
+}
+
+// NewTypedSingleOpsPipe returns a new instance of Pipe whose single ops take values of the in type and, once all of
+// them were applied, return values of the out type
+func NewTypedSingleOpsPipe(ds string, in, out column.Type, so []TypedOp) *Pipe {
 	return &Pipe{
 		Description: ds,
+		inType:      in,
+		outType:     out,
 		singleOps:   so,
 		aggregateOp: nil,
 	}
 }
 
-// NewAggregateOpPipe returns a new instance of Pipe with an aggregate function
-func NewAggregateOpPipe(ds string, ao func([]float64) (float64, error)) *Pipe {
+// NewTypedAggregateOpPipe returns a new instance of Pipe with an aggregate function that takes values of the in type
+// and returns a value of the out type
+func NewTypedAggregateOpPipe(ds string, in, out column.Type, ao TypedAggregateOp) *Pipe {
 	return &Pipe{
 		Description: ds,
+		inType:      in,
+		outType:     out,
 		singleOps:   nil,
 		aggregateOp: ao,
 	}
@@ -37,83 +142,506 @@ func NewAggregateOpPipe(ds string, ao func([]float64) (float64, error)) *Pipe {
 
 // SetInput sets the inputs to the pipe, should only be accessed by a source
 func (p *Pipe) SetInput(in map[string][]float64) {
-	p.input = in
+	p.input = fromFloats(in)
 }
 
-// GetInput returns the input that was specified to the pipe
+// GetInput returns the input that was specified to the pipe, only numeric columns are part of it
 func (p *Pipe) GetInput() map[string][]float64 {
+	return toFloats(p.input)
+}
+
+// SetTypedInput sets the typed input columns of the pipe, should only be accessed by a source
+func (p *Pipe) SetTypedInput(in map[string]*column.Column) {
+	p.input = in
+}
+
+// GetTypedInput returns the typed input columns that were specified to the pipe
+func (p *Pipe) GetTypedInput() map[string]*column.Column {
 	return p.input
 }
 
+// SetOutputName names the output column of the pipe, so that several pipes can flow the same input column into
+// distinct output columns, e.g "a_sum" and "a_plus1". The output column is named after the input column by default, a
+// pipe with an output name cannot flow several input columns
+func (p *Pipe) SetOutputName(name string) {
+	p.outputName = name
+}
+
+// GetOutputName returns the name of the output column of the pipe when it flows the given input column
+func (p *Pipe) GetOutputName(col string) string {
+	if p.outputName != "" {
+		return p.outputName
+	}
+	return col
+}
+
+// SetUpstream makes the output columns of the given pipes the input of this pipe, so that pipes can be chained. The
+// structure flows the pipe once all of its upstream pipes flowed successfully
+func (p *Pipe) SetUpstream(ups ...*Pipe) {
+	p.upstream = ups
+}
+
+// GetUpstream returns the pipes whose output is the input of this pipe, nil when the pipe is fed by a source
+func (p *Pipe) GetUpstream() []*Pipe {
+	return p.upstream
+}
+
 // SetOutput sets the output of the pipe to a custom one that is not computed by Flow
 // this is mostly implemented for testing purposes as we would otherwise have to set up
 // CSV files for testing the Sink package
 func (p *Pipe) SetOutput(ot map[string][]float64) {
-	p.output = ot
+	p.output = fromFloats(ot)
 }
 
-// GetOutput allows a consumer to get the output of this pipe
+// GetOutput allows a consumer to get the output of this pipe, only numeric columns are part of it
 func (p *Pipe) GetOutput() map[string][]float64 {
+	return toFloats(p.output)
+}
+
+// SetTypedOutput sets the typed output of the pipe to a custom one that is not computed by Flow, see SetOutput
+func (p *Pipe) SetTypedOutput(ot map[string]*column.Column) {
+	p.output = ot
+}
+
+// GetTypedOutput allows a consumer to get the typed output columns of this pipe
+func (p *Pipe) GetTypedOutput() map[string]*column.Column {
 	return p.output
 }
 
+// fromFloats converts a mapping of column names to float64 values into Float columns
+func fromFloats(m map[string][]float64) map[string]*column.Column {
+	if m == nil {
+		return nil
+	}
+	cols := make(map[string]*column.Column, len(m))
+	for k, v := range m {
+		cols[k] = column.FromFloats(k, v)
+	}
+	return cols
+}
+
+// toFloats converts the numeric columns of the given mapping into float64 values, other columns are left out
+func toFloats(cols map[string]*column.Column) map[string][]float64 {
+	if cols == nil {
+		return nil
+	}
+	m := make(map[string][]float64, len(cols))
+	for k, c := range cols {
+		if vs, err := c.Floats(); err == nil {
+			m[k] = vs
+		}
+	}
+	return m
+}
+
+// SetReporter makes the pipe report its progress to r every given number of rows, as well as once done. An interval
+// that is not positive means DefaultReportEvery
+func (p *Pipe) SetReporter(r progress.Reporter, every int) {
+	if every <= 0 {
+		every = DefaultReportEvery
+	}
+	p.reporter = r
+	p.reportEvery = every
+}
+
+// GetReporter returns the reporter the pipe reports its progress to, nil if progress is not reported
+func (p *Pipe) GetReporter() progress.Reporter {
+	return p.reporter
+}
+
+// reporterKey is the key of the reporter of a flow in its context, see WithReporter
+type reporterKey struct{}
+
+// flowReporter is the reporter of a flow along with its interval
+type flowReporter struct {
+	r     progress.Reporter
+	every int
+}
+
+// WithReporter returns a copy of the given context that makes the pipes flowing with it report their progress to r
+// every given number of rows, unless they have a reporter of their own, see SetReporter. The reporter only applies to
+// the flows of that context, pipes are left as they are. An interval that is not positive means DefaultReportEvery
+func WithReporter(ctx context.Context, r progress.Reporter, every int) context.Context {
+	if every <= 0 {
+		every = DefaultReportEvery
+	}
+	return context.WithValue(ctx, reporterKey{}, flowReporter{r: r, every: every})
+}
+
+// useReporter makes the current flow report to the reporter of the pipe or, when it has none, to the reporter of the
+// given context, if any
+func (p *Pipe) useReporter(ctx context.Context) {
+	p.reporting, p.reportingEvery = p.reporter, p.reportEvery
+	if p.reporting != nil {
+		return
+	}
+	if fr, ok := ctx.Value(reporterKey{}).(flowReporter); ok {
+		p.reporting, p.reportingEvery = fr.r, fr.every
+	}
+}
+
+// report sends the progress of the pipe to the reporter of the current flow, if any
+func (p *Pipe) report(processed, total int) {
+	if p.reporting == nil {
+		return
+	}
+	p.reporting.Report(progress.Progress{
+		Description: p.Description,
+		Processed:   processed,
+		Total:       total,
+		Elapsed:     time.Since(p.start),
+	})
+}
+
 // GetFlowDuration tells how long the Flow operation needed to process the pipeline input
 func (p *Pipe) GetFlowDuration() time.Duration {
 	return p.end.Sub(p.start)
 }
 
+// GetRows returns the number of rows that entered and left the pipe during its last flow, or stream so far. Filters,
+// error policies and aggregates make the pipe output fewer rows than it received
+func (p *Pipe) GetRows() (int, int) {
+	return p.rowsIn, p.rowsOut
+}
+
+// GetNulls returns the number of null values the pipe output during its last flow, or stream so far
+func (p *Pipe) GetNulls() int {
+	return p.nulls
+}
+
+// count adds the rows of the given input and output columns to the rows that entered and left the pipe, and the null
+// values of the output to the nulls it output
+func (p *Pipe) count(in, out map[string]*column.Column) {
+	p.rowsIn += maxLen(in)
+	p.rowsOut += maxLen(out)
+	for _, c := range out {
+		p.nulls += c.NullCount()
+	}
+}
+
+// maxLen returns the length of the longest of the given columns
+func maxLen(cols map[string]*column.Column) int {
+	n := 0
+	for _, c := range cols {
+		if c.Len() > n {
+			n = c.Len()
+		}
+	}
+	return n
+}
+
 // Flow flows the specified input through the specified pipe singleOp and stores the output
 func (p *Pipe) Flow() error {
+	return p.FlowContext(context.Background())
+}
+
+// FlowContext flows the specified input through the pipe ops until done or until the given context is cancelled or
+// exceeds its deadline, in which case the context error is returned wrapped with the pipe Description. Failed flows
+// are retried according to the flow retry policy of the pipe, see SetFlowRetryPolicy, within the timeout of the pipe,
+// see SetTimeout
+func (p *Pipe) FlowContext(ctx context.Context) error {
+	if err := p.checkRetryPolicies(); err != nil {
+		return err
+	}
+	start := time.Now()
+	p.deadline = time.Time{}
+	if p.timeout > 0 {
+		p.deadline = start.Add(p.timeout)
+	}
+	ctx, cancel := p.withDeadline(ctx)
+	defer cancel()
+	p.useReporter(ctx)
+	p.opRetries, p.flowRetries = 0, 0
+	retries, err := p.flowRetry.do(ctx, func() error {
+		return p.flow(ctx)
+	})
+	// the flow duration covers all the attempts
+	p.start = start
+	p.flowRetries = retries
+	return err
+}
+
+// flow makes a single attempt at flowing the input through the pipe ops, see FlowContext
+func (p *Pipe) flow(ctx context.Context) (err error) {
 	p.start = time.Now()
+	p.dropped = 0
+	p.errors = 0
+	p.rowsIn, p.rowsOut, p.nulls = 0, 0, 0
+	defer func() {
+		p.end = time.Now()
+		if err == nil {
+			p.count(p.input, p.output)
+		}
+	}()
 	if p.input == nil {
 		return fmt.Errorf("cannot flow nil input through specified singleOps")
 	}
-	if p.singleOps != nil && p.aggregateOp != nil {
-		// this should not happen
-		return fmt.Errorf("cannot perform single ops and aggregate ops")
+	if err := p.checkOps(); err != nil {
+		return err
+	}
+	if err := p.checkOutputNames(p.input); err != nil {
+		return err
+	}
+	if err := p.checkErrorPolicy(); err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := p.closeDeadLetter(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+	if err := ctx.Err(); err != nil {
+		return p.stopped(err)
+	}
+	if err := p.convertInput(); err != nil {
+		return err
 	}
 
-	p.output = map[string][]float64{}
+	p.output = map[string]*column.Column{}
 	if p.singleOps != nil {
-		return p.flowThroughSingleOps()
+		return p.flowThroughSingleOps(ctx)
 	}
 	if p.aggregateOp != nil {
-		return p.flowThroughAggregateOp()
+		return p.flowThroughAggregateOp(ctx)
+	}
+	if p.newAccumulator != nil {
+		return p.flowThroughAccumulator(ctx)
+	}
+	if p.rowOp != nil {
+		return p.flowThroughRowOp(ctx)
+	}
+	if p.window != nil {
+		return p.flowThroughWindow(ctx)
+	}
+	if p.groupBy != nil {
+		return p.flowThroughGroupBy(ctx)
+	}
+	if p.aggregates != nil {
+		return p.flowThroughAggregates(ctx)
 	}
 	return nil
 }
 
+// checkOps checks that the pipe performs a single kind of ops
+func (p *Pipe) checkOps() error {
+	kinds := 0
+	for _, set := range []bool{p.singleOps != nil, p.aggregateOp != nil, p.newAccumulator != nil, p.rowOp != nil, p.window != nil, p.groupBy != nil, p.aggregates != nil} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds > 1 {
+		// this should not happen
+		return fmt.Errorf("cannot perform single ops and aggregate ops")
+	}
+	if err := p.checkAggregates(); err != nil {
+		return err
+	}
+	if err := p.checkGroupBy(); err != nil {
+		return err
+	}
+	if err := p.checkRowOp(); err != nil {
+		return err
+	}
+	return p.checkWindow()
+}
+
+// checkOutputNames checks that the pipe outputs distinct columns for the given input, e.g an output name is only set
+// on pipes that flow a single input column
+func (p *Pipe) checkOutputNames(in map[string]*column.Column) error {
+	cols := make([]string, 0, len(in))
+	for c := range in {
+		cols = append(cols, c)
+	}
+	sort.Strings(cols)
+	seen := map[string]bool{}
+	for _, name := range p.GetOutputNames(cols) {
+		if seen[name] {
+			return p.errorf("outputs column (%s) more than once, output names need a single input column", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// convertInput converts the input columns to the type the ops of the pipe take, e.g Int columns flowing through float64
+// ops are converted to Float. Row ops and group bys receive the values of their input columns as they are
+func (p *Pipe) convertInput() error {
+	if p.rowOp != nil || p.groupBy != nil {
+		return nil
+	}
+	for k, c := range p.input {
+		converted, err := c.As(p.inType)
+		if err != nil {
+			return p.errorf("cannot flow input, err: %v", err)
+		}
+		p.input[k] = converted
+	}
+	return nil
+}
+
+// stopped wraps the given context error with the Description of the pipe that was stopped, a pipe that exceeded its
+// own timeout says so
+func (p *Pipe) stopped(err error) error {
+	if p.timedOut(err) {
+		return p.errorf("exceeded its timeout of %v, err: %w", p.timeout, err)
+	}
+	return p.errorf("stopped, err: %w", err)
+}
+
 // flowThroughSingleOps does the work of the specified single ops on the pipeline
-func (p *Pipe) flowThroughSingleOps() error {
-	for col, rows := range p.input {
-		// TODO: use a sync/wait group to execute this in parallel
-		p.output[col] = make([]float64, len(rows))
-		for i, val := range rows {
-			newVal, err := p.singleOps[0](val)
-			if err != nil {
-				return fmt.Errorf("failed to apply op to val %v on row %v with op msg: %v", val, i, err)
+func (p *Pipe) flowThroughSingleOps(ctx context.Context) error {
+	total := p.totalRows()
+	processed := 0
+	p.report(processed, total)
+	for _, col := range p.inputColumns() {
+		in, err := p.filter(ctx, p.input[col], 0)
+		if err != nil {
+			return err
+		}
+		processed += p.input[col].Len() - in.Len()
+		out := column.Zeros(p.GetOutputName(col), p.outType, in.Len())
+		out.Rows = in.Rows
+		// kept holds the rows that are left once the error policy dropped failed ones
+		kept := make([]int, 0, in.Len())
+		for i, val := range in.Values {
+			if err := ctx.Err(); err != nil {
+				return p.stopped(err)
 			}
-			for _, op := range p.singleOps[1:] {
-				newVal, err = op(val)
+			if in.IsNull(i) {
+				// null values flow through as null
+				out.SetNull(i)
+				kept = append(kept, i)
+			} else {
+				newVal, keep, err := p.flowValue(ctx, val, in.Row(i))
 				if err != nil {
-					return fmt.Errorf("failed to apply op to val %v on row %v with op msg: %v", val, i, err)
+					return err
+				}
+				if keep {
+					if newVal == nil {
+						out.SetNull(i)
+					} else {
+						out.Values[i] = newVal
+					}
+					kept = append(kept, i)
 				}
 			}
-			p.output[col][i] = newVal
+			processed++
+			if p.reporting != nil && processed%p.reportingEvery == 0 && processed != total {
+				p.report(processed, total)
+			}
+		}
+		if len(kept) < in.Len() {
+			out = out.Take(kept)
 		}
+		p.output[out.Name] = out
 	}
+	p.report(processed, total)
 	return nil
 }
 
-// flowThroughAggregateOp does the work of the specific aggregate op on the pipeline
-func (p *Pipe) flowThroughAggregateOp() error {
+// flowValue applies all the single ops to the given value of the given row and, when they fail, the error policy of
+// the pipe. It returns the output value, nil for null, and whether the row is kept
+func (p *Pipe) flowValue(ctx context.Context, val interface{}, row int) (interface{}, bool, error) {
+	newVal, err := p.applySingleOps(ctx, val, row)
+	if err == nil {
+		return newVal, true, nil
+	}
+	var e *OpError
+	if !errors.As(err, &e) {
+		return nil, false, err
+	}
+	return p.handleError(e, err)
+}
+
+// applySingleOps applies all the single ops, in order, to the given value of the given row, failed ops return an
+// OpError
+func (p *Pipe) applySingleOps(ctx context.Context, val interface{}, row int) (interface{}, error) {
+	newVal := val
+	for i, op := range p.singleOps {
+		op, in := op, newVal
+		var err error
+		newVal, err = runOp(ctx, p, i, row, func(ctx context.Context) (interface{}, error) {
+			return op(ctx, in)
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, p.stoppedOn(ctx.Err(), i, row)
+			}
+			return nil, &OpError{Row: row, Value: val, Op: i, Err: err}
+		}
+	}
+	if err := p.outType.Check(newVal); err != nil {
+		// the value of the wrong type is returned by the last op
+		return nil, &OpError{Row: row, Value: val, Op: len(p.singleOps) - 1, Err: err}
+	}
+	return newVal, nil
+}
+
+// inputColumns returns the names of the input columns in alphabetical order so pipes flow deterministically
+func (p *Pipe) inputColumns() []string {
+	cols := make([]string, 0, len(p.input))
+	for col := range p.input {
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+	return cols
+}
+
+// totalRows returns the number of rows across all the input columns of the pipe
+func (p *Pipe) totalRows() int {
+	total := 0
+	for _, c := range p.input {
+		total += c.Len()
+	}
+	return total
+}
+
+// aggregateResult holds the outcome of an aggregate op that runs in the background
+type aggregateResult struct {
+	val     interface{}
+	retries int // number of times the op was retried
+	err     error
+}
+
+// flowThroughAggregateOp does the work of the specific aggregate op on the pipeline. The op runs in the background so
+// that a cancelled context stops the pipe promptly even when the op itself does not observe the context
+func (p *Pipe) flowThroughAggregateOp(ctx context.Context) error {
+	total := p.totalRows()
+	processed := 0
+	p.report(processed, total)
 	// there's a single col and row per pipe input, but using "for" here makes the pipe agnostic to the name of the col
-	for col, rows := range p.input {
-		p.output[col] = make([]float64, 1)
-		if val, err := p.aggregateOp(rows); err != nil {
-			return fmt.Errorf("failed to perform aggregate op on col (%v), err: %v", col, err)
-		} else {
-			p.output[col][0] = val
+	for _, col := range p.inputColumns() {
+		in, err := p.filter(ctx, p.input[col], 0)
+		if err != nil {
+			return err
+		}
+		rows := in.ValidValues()
+		res := make(chan aggregateResult, 1)
+		go func(rows []interface{}) {
+			val, retries, err := callOp(ctx, p, 0, -1, func(ctx context.Context) (interface{}, error) {
+				return p.aggregateOp(ctx, rows)
+			})
+			res <- aggregateResult{val: val, retries: retries, err: err}
+		}(rows)
+		select {
+		case <-ctx.Done():
+			return p.stopped(ctx.Err())
+		case r := <-res:
+			p.opRetries += r.retries
+			if r.err != nil {
+				if ctx.Err() != nil {
+					return p.stopped(ctx.Err())
+				}
+				return fmt.Errorf("failed to perform aggregate op on col (%v), err: %w", col, r.err)
+			}
+			if err := p.outType.Check(r.val); err != nil {
+				return fmt.Errorf("failed to perform aggregate op on col (%v), err: %v", col, err)
+			}
+			name := p.GetOutputName(col)
+			p.output[name] = column.New(name, p.outType, []interface{}{r.val})
+			processed += p.input[col].Len()
+			p.report(processed, total)
 		}
 	}
 	return nil
@@ -1,10 +1,16 @@
 package pipe
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/flaviuvadan/pipe-flow/column"
+	"github.com/flaviuvadan/pipe-flow/progress"
 )
 
 func TestNewSingleOpsPipe_Flow(t *testing.T) {
@@ -163,3 +169,411 @@ func TestNewAggregateOpPipe_Flow(t *testing.T) {
 		})
 	}
 }
+
+func TestPipe_FlowContext(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		pipe        func(cancel context.CancelFunc) *Pipe
+		pipeIn      map[string][]float64
+		expectedErr error
+	}{
+		{
+			name: "test_single_ops_stop_on_cancelled_context",
+			pipe: func(cancel context.CancelFunc) *Pipe {
+				return NewSingleOpsPipeContext("single", []SingleOpContext{
+					func(_ context.Context, v float64) (float64, error) {
+						cancel()
+						return v, nil
+					},
+				})
+			},
+			pipeIn: map[string][]float64{
+				"a": {1, 2, 3},
+			},
+			expectedErr: fmt.Errorf("pipe (single) stopped, err: context canceled"),
+		},
+		{
+			name: "test_aggregate_op_stops_on_cancelled_context",
+			pipe: func(cancel context.CancelFunc) *Pipe {
+				return NewAggregateOpPipeContext("aggregate", func(ctx context.Context, _ []float64) (float64, error) {
+					cancel()
+					<-ctx.Done()
+					return 0, ctx.Err()
+				})
+			},
+			pipeIn: map[string][]float64{
+				"a": {1, 2, 3},
+			},
+			expectedErr: fmt.Errorf("pipe (aggregate) stopped, err: context canceled"),
+		},
+		{
+			name: "test_aggregate_op_ignoring_context_stops_promptly",
+			pipe: func(cancel context.CancelFunc) *Pipe {
+				return NewAggregateOpPipe("blocking", func(_ []float64) (float64, error) {
+					cancel()
+					time.Sleep(time.Hour)
+					return 0, nil
+				})
+			},
+			pipeIn: map[string][]float64{
+				"a": {1, 2, 3},
+			},
+			expectedErr: fmt.Errorf("pipe (blocking) stopped, err: context canceled"),
+		},
+		{
+			name: "test_flows_when_context_is_not_done",
+			pipe: func(_ context.CancelFunc) *Pipe {
+				return NewSingleOpsPipeContext("single", []SingleOpContext{
+					func(_ context.Context, v float64) (float64, error) {
+						return v + 1, nil
+					},
+				})
+			},
+			pipeIn: map[string][]float64{
+				"a": {1, 2, 3},
+			},
+			expectedErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			p := tt.pipe(cancel)
+			p.SetInput(tt.pipeIn)
+			err := p.FlowContext(ctx)
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				assert.True(t, errors.Is(err, context.Canceled))
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNewSingleOpsPipe_Flow_ChainsOps(t *testing.T) {
+	t.Parallel()
+	p := NewSingleOpsPipe("chain", []func(float64) (float64, error){
+		func(v float64) (float64, error) {
+			return v + 1, nil
+		},
+		func(v float64) (float64, error) {
+			return v * 10, nil
+		},
+	})
+	p.SetInput(map[string][]float64{"a": {1, 2}})
+	assert.NoError(t, p.Flow())
+	assert.Equal(t, []float64{20, 30}, p.GetOutput()["a"])
+	assert.True(t, p.GetFlowDuration() >= 0)
+}
+
+func TestPipe_SetReporter(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name              string
+		pipe              *Pipe
+		every             int
+		pipeIn            map[string][]float64
+		expectedProcessed []int
+	}{
+		{
+			name: "test_single_ops_report_at_interval",
+			pipe: NewSingleOpsPipe("single", []func(float64) (float64, error){
+				func(v float64) (float64, error) {
+					return v, nil
+				},
+			}),
+			every: 2,
+			pipeIn: map[string][]float64{
+				"a": {1, 2, 3, 4, 5},
+			},
+			expectedProcessed: []int{0, 2, 4, 5},
+		},
+		{
+			name: "test_aggregate_op_reports_start_and_end",
+			pipe: NewAggregateOpPipe("aggregate", func(values []float64) (float64, error) {
+				return 0, nil
+			}),
+			every: 2,
+			pipeIn: map[string][]float64{
+				"a": {1, 2, 3, 4, 5},
+			},
+			expectedProcessed: []int{0, 5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := progress.NewChanReporter(len(tt.expectedProcessed))
+			tt.pipe.SetReporter(r, tt.every)
+			tt.pipe.SetInput(tt.pipeIn)
+			assert.NoError(t, tt.pipe.Flow())
+			r.Close()
+			var processed []int
+			for u := range r.Updates() {
+				assert.Equal(t, tt.pipe.Description, u.Description)
+				assert.Equal(t, 5, u.Total)
+				processed = append(processed, u.Processed)
+			}
+			assert.Equal(t, tt.expectedProcessed, processed)
+		})
+	}
+}
+
+func TestNewTypedSingleOpsPipe_Flow(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		in          column.Type
+		out         column.Type
+		ops         []TypedOp
+		pipeIn      *column.Column
+		pipeOut     *column.Column
+		expectedErr error
+	}{
+		{
+			name: "test_flows_strings_into_ints",
+			in:   column.String,
+			out:  column.Int,
+			ops: []TypedOp{
+				func(_ context.Context, v interface{}) (interface{}, error) {
+					return int64(len(v.(string))), nil
+				},
+			},
+			pipeIn:  column.New("a", column.String, []interface{}{"x", "yy"}),
+			pipeOut: column.New("a", column.Int, []interface{}{int64(1), int64(2)}),
+		},
+		{
+			name: "test_returns_err_when_op_returns_wrong_type",
+			in:   column.String,
+			out:  column.Int,
+			ops: []TypedOp{
+				func(_ context.Context, v interface{}) (interface{}, error) {
+					return v, nil
+				},
+			},
+			pipeIn:      column.New("a", column.String, []interface{}{"x"}),
+			expectedErr: fmt.Errorf("failed to apply op to val x on row 0 with op msg: value x of type string is not of type int64"),
+		},
+		{
+			name: "test_returns_err_when_input_type_does_not_match",
+			in:   column.Bool,
+			out:  column.Bool,
+			ops: []TypedOp{
+				func(_ context.Context, v interface{}) (interface{}, error) {
+					return v, nil
+				},
+			},
+			pipeIn:      column.New("a", column.String, []interface{}{"x"}),
+			expectedErr: fmt.Errorf("pipe (test_returns_err_when_input_type_does_not_match) cannot flow input, err: col (a) of type string cannot be used as bool"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewTypedSingleOpsPipe(tt.name, tt.in, tt.out, tt.ops)
+			p.SetTypedInput(map[string]*column.Column{"a": tt.pipeIn})
+			err := p.Flow()
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.pipeOut, p.GetTypedOutput()["a"])
+		})
+	}
+}
+
+func TestNewTypedAggregateOpPipe_Flow(t *testing.T) {
+	t.Parallel()
+	p := NewTypedAggregateOpPipe("count_true", column.Bool, column.Int, func(_ context.Context, values []interface{}) (interface{}, error) {
+		n := int64(0)
+		for _, v := range values {
+			if v.(bool) {
+				n++
+			}
+		}
+		return n, nil
+	})
+	p.SetTypedInput(map[string]*column.Column{"a": column.New("a", column.Bool, []interface{}{true, false, true})})
+	assert.NoError(t, p.Flow())
+	assert.Equal(t, column.New("a", column.Int, []interface{}{int64(2)}), p.GetTypedOutput()["a"])
+}
+
+func TestNewSingleOpsPipe_Flow_ConvertsInts(t *testing.T) {
+	t.Parallel()
+	p := NewSingleOpsPipe("ints", []func(float64) (float64, error){
+		func(v float64) (float64, error) {
+			return v / 2, nil
+		},
+	})
+	p.SetTypedInput(map[string]*column.Column{"a": column.New("a", column.Int, []interface{}{int64(1), int64(2)})})
+	assert.NoError(t, p.Flow())
+	assert.Equal(t, []float64{0.5, 1}, p.GetOutput()["a"])
+}
+
+func TestPipe_Flow_Nulls(t *testing.T) {
+	t.Parallel()
+	in := column.New("a", column.Float, nil)
+	in.Push(1.0)
+	in.Push(nil)
+	in.Push(3.0)
+
+	single := NewSingleOpsPipe("single", []func(float64) (float64, error){
+		func(v float64) (float64, error) {
+			return v + 1, nil
+		},
+	})
+	single.SetTypedInput(map[string]*column.Column{"a": in})
+	assert.NoError(t, single.Flow())
+	out := single.GetTypedOutput()["a"]
+	assert.Equal(t, 2.0, out.Values[0])
+	assert.True(t, out.IsNull(1))
+	assert.Equal(t, 4.0, out.Values[2])
+
+	aggregate := NewAggregateOpPipe("aggregate", func(values []float64) (float64, error) {
+		return float64(len(values)), nil
+	})
+	aggregate.SetTypedInput(map[string]*column.Column{"a": in})
+	assert.NoError(t, aggregate.Flow())
+	assert.Equal(t, []float64{2}, aggregate.GetOutput()["a"])
+}
+
+func TestPipe_SetOutputName(t *testing.T) {
+	sum := func(vs []float64) (float64, error) {
+		total := 0.0
+		for _, v := range vs {
+			total += v
+		}
+		return total, nil
+	}
+	plusOne := []func(float64) (float64, error){
+		func(v float64) (float64, error) {
+			return v + 1, nil
+		},
+	}
+	tests := []struct {
+		name     string
+		pipe     *Pipe
+		outName  string
+		expected map[string][]float64
+	}{
+		{
+			name:     "test_names_output_after_input_by_default",
+			pipe:     NewSingleOpsPipe("test", plusOne),
+			expected: map[string][]float64{"a": {2, 3}},
+		},
+		{
+			name:     "test_names_single_ops_output",
+			pipe:     NewSingleOpsPipe("test", plusOne),
+			outName:  "a_plus1",
+			expected: map[string][]float64{"a_plus1": {2, 3}},
+		},
+		{
+			name:     "test_names_aggregate_output",
+			pipe:     NewAggregateOpPipe("test", sum),
+			outName:  "a_sum",
+			expected: map[string][]float64{"a_sum": {3}},
+		},
+		{
+			name:     "test_names_accumulator_output",
+			pipe:     NewAccumulatorPipe("test", newSumAccumulator),
+			outName:  "a_sum",
+			expected: map[string][]float64{"a_sum": {3}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.pipe.SetOutputName(tt.outName)
+			tt.pipe.SetInput(map[string][]float64{"a": {1, 2}})
+			assert.NoError(t, tt.pipe.Flow())
+			assert.Equal(t, tt.expected, tt.pipe.GetOutput())
+			for k, c := range tt.pipe.GetTypedOutput() {
+				assert.Equal(t, k, c.Name)
+			}
+		})
+	}
+}
+
+func TestPipe_SetOutputName_SeveralInputs(t *testing.T) {
+	t.Parallel()
+	p := NewSingleOpsPipe("test", nil)
+	p.SetOutputName("out")
+	p.SetInput(map[string][]float64{"a": {1}, "b": {2}})
+	assert.EqualError(t, p.Flow(), "pipe (test) outputs column (out) more than once, output names need a single input column")
+	assert.NoError(t, p.Begin())
+	_, err := p.FlowChunk(context.Background(), map[string]*column.Column{
+		"a": column.FromFloats("a", []float64{1}),
+		"b": column.FromFloats("b", []float64{2}),
+	})
+	assert.EqualError(t, err, "pipe (test) outputs column (out) more than once, output names need a single input column")
+}
+
+func TestPipe_GetRows(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name            string
+		pipe            *Pipe
+		pipeIn          map[string]*column.Column
+		expectedRowsIn  int
+		expectedRowsOut int
+		expectedNulls   int
+	}{
+		{
+			name: "test_counts_rows_of_single_ops",
+			pipe: NewSingleOpsPipe("single", []func(float64) (float64, error){
+				func(v float64) (float64, error) {
+					return v, nil
+				},
+			}),
+			pipeIn:          map[string]*column.Column{"a": floats("a", 1.0, nil, 3.0)},
+			expectedRowsIn:  3,
+			expectedRowsOut: 3,
+			expectedNulls:   1,
+		},
+		{
+			name: "test_counts_rows_of_filters",
+			pipe: NewFilterPipe("filter", func(v float64) (bool, error) {
+				return v > 1, nil
+			}),
+			pipeIn:          map[string]*column.Column{"a": floats("a", 1.0, 2.0, 3.0)},
+			expectedRowsIn:  3,
+			expectedRowsOut: 2,
+		},
+		{
+			name: "test_counts_rows_of_aggregates",
+			pipe: NewAggregateOpPipe("aggregate", func(values []float64) (float64, error) {
+				return float64(len(values)), nil
+			}),
+			pipeIn:          map[string]*column.Column{"a": floats("a", 1.0, 2.0, 3.0)},
+			expectedRowsIn:  3,
+			expectedRowsOut: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.pipe.SetTypedInput(tt.pipeIn)
+			assert.NoError(t, tt.pipe.Flow())
+			in, out := tt.pipe.GetRows()
+			assert.Equal(t, tt.expectedRowsIn, in)
+			assert.Equal(t, tt.expectedRowsOut, out)
+			assert.Equal(t, tt.expectedNulls, tt.pipe.GetNulls())
+		})
+	}
+}
+
+func TestPipe_GetRows_Stream(t *testing.T) {
+	p := NewAccumulatorPipe("accumulator", func() Accumulator {
+		return &sumAccumulator{}
+	})
+	assert.NoError(t, p.Begin())
+	for _, chunk := range []map[string][]float64{{"a": {1, 2}}, {"a": {3}}} {
+		_, err := p.FlowChunk(context.Background(), fromFloats(chunk))
+		assert.NoError(t, err)
+	}
+	_, err := p.End()
+	assert.NoError(t, err)
+	in, out := p.GetRows()
+	assert.Equal(t, 3, in)
+	assert.Equal(t, 1, out)
+}
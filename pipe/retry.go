@@ -0,0 +1,101 @@
+package pipe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy tells a pipe how to retry what fails transiently, e.g ops that call into a lazily loaded lookup table
+type RetryPolicy struct {
+	MaxAttempts int              // number of times to try at most, 0 and 1 mean nothing is retried
+	Backoff     time.Duration    // wait before the first retry, doubled before every following retry
+	MaxBackoff  time.Duration    // upper bound of the wait between retries, 0 means no bound
+	Jitter      float64          // fraction of every wait, within [0, 1], that is randomized so retries spread out
+	Retryable   func(error) bool // tells whether an error is transient and worth retrying, nil means all errors are
+}
+
+// SetRetryPolicy sets how the pipe retries its failing ops: single ops, row ops, aggregate ops, broadcast ops, window
+// ops and the ops of group bys are retried on their own, with the same input, before the error policy of the pipe sees
+// their failure. Accumulators are not retried as a failed Add may have been partially applied
+func (p *Pipe) SetRetryPolicy(rp RetryPolicy) {
+	p.opRetry = rp
+}
+
+// SetFlowRetryPolicy sets how the pipe retries whole flows that fail, see FlowContext. Streams are not retried as a
+// whole since the chunks that flowed already were passed on
+func (p *Pipe) SetFlowRetryPolicy(rp RetryPolicy) {
+	p.flowRetry = rp
+}
+
+// GetRetries returns the number of times the ops of the pipe were retried during its last flow, or stream so far, and
+// the number of times the whole flow was retried
+func (p *Pipe) GetRetries() (int, int) {
+	return p.opRetries, p.flowRetries
+}
+
+// check checks that the retry policy can be applied
+func (rp RetryPolicy) check() error {
+	if rp.MaxAttempts < 0 {
+		return fmt.Errorf("cannot make %d attempts", rp.MaxAttempts)
+	}
+	if rp.Backoff < 0 || rp.MaxBackoff < 0 {
+		return fmt.Errorf("cannot back off for a negative duration")
+	}
+	if rp.Jitter < 0 || rp.Jitter > 1 {
+		return fmt.Errorf("jitter %v is not within [0, 1]", rp.Jitter)
+	}
+	return nil
+}
+
+// checkRetryPolicies checks that the retry policies of the pipe can be applied
+func (p *Pipe) checkRetryPolicies() error {
+	if err := p.opRetry.check(); err != nil {
+		return p.errorf("cannot retry its ops, err: %v", err)
+	}
+	if err := p.flowRetry.check(); err != nil {
+		return p.errorf("cannot retry its flow, err: %v", err)
+	}
+	return nil
+}
+
+// retryable tells whether the given error is worth retrying, errors of a done context never are
+func (rp RetryPolicy) retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return rp.Retryable == nil || rp.Retryable(err)
+}
+
+// wait returns the wait before the given retry, the first one being 1, with exponential backoff and jitter
+func (rp RetryPolicy) wait(retry int) time.Duration {
+	d := rp.Backoff
+	for i := 1; i < retry && (rp.MaxBackoff == 0 || d < rp.MaxBackoff) && d < math.MaxInt64/2; i++ {
+		d *= 2
+	}
+	if rp.MaxBackoff > 0 && d > rp.MaxBackoff {
+		d = rp.MaxBackoff
+	}
+	return d - time.Duration(rp.Jitter*rand.Float64()*float64(d))
+}
+
+// do calls try until it succeeds, fails with an error that is not retryable or runs out of attempts, and returns the
+// number of retries along with the last error. Waiting for a retry stops when the given context is done
+func (rp RetryPolicy) do(ctx context.Context, try func() error) (int, error) {
+	for retries := 0; ; retries++ {
+		err := try()
+		if err == nil || retries+1 >= rp.MaxAttempts || !rp.retryable(err) || ctx.Err() != nil {
+			return retries, err
+		}
+		t := time.NewTimer(rp.wait(retries + 1))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return retries, err
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package pipe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// errTransient is a test error that the classifier of retry policies retries
+var errTransient = errors.New("transient")
+
+// failTimes returns a single op that fails with the given error the given number of times before it succeeds
+func failTimes(n int, err error) func(float64) (float64, error) {
+	calls := 0
+	return func(v float64) (float64, error) {
+		calls++
+		if calls <= n {
+			return 0, err
+		}
+		return v * 2, nil
+	}
+}
+
+func TestPipe_SetRetryPolicy(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name            string
+		op              func(float64) (float64, error)
+		policy          RetryPolicy
+		expected        []float64
+		expectedRetries int
+		expectedErr     error
+	}{
+		{
+			name:        "test_does_not_retry_by_default",
+			op:          failTimes(1, errTransient),
+			expectedErr: fmt.Errorf("failed to apply op to val 1 on row 0 with op msg: transient"),
+		},
+		{
+			name:            "test_retries_failed_ops",
+			op:              failTimes(2, errTransient),
+			policy:          RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond},
+			expected:        []float64{2, 4},
+			expectedRetries: 2,
+		},
+		{
+			name:        "test_fails_once_attempts_run_out",
+			op:          failTimes(3, errTransient),
+			policy:      RetryPolicy{MaxAttempts: 3},
+			expectedErr: fmt.Errorf("failed to apply op to val 1 on row 0 with op msg: transient"),
+		},
+		{
+			name: "test_does_not_retry_errors_that_are_not_retryable",
+			op:   failTimes(1, fmt.Errorf("permanent")),
+			policy: RetryPolicy{MaxAttempts: 3, Retryable: func(err error) bool {
+				return errors.Is(err, errTransient)
+			}},
+			expectedErr: fmt.Errorf("failed to apply op to val 1 on row 0 with op msg: permanent"),
+		},
+		{
+			name:        "test_returns_err_on_invalid_jitter",
+			op:          failTimes(0, nil),
+			policy:      RetryPolicy{MaxAttempts: 3, Jitter: 2},
+			expectedErr: fmt.Errorf("pipe (test_returns_err_on_invalid_jitter) cannot retry its ops, err: jitter 2 is not within [0, 1]"),
+		},
+		{
+			name:        "test_returns_err_on_negative_attempts",
+			op:          failTimes(0, nil),
+			policy:      RetryPolicy{MaxAttempts: -1},
+			expectedErr: fmt.Errorf("pipe (test_returns_err_on_negative_attempts) cannot retry its ops, err: cannot make -1 attempts"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewSingleOpsPipe(tt.name, []func(float64) (float64, error){tt.op})
+			p.SetRetryPolicy(tt.policy)
+			p.SetInput(map[string][]float64{"a": {1, 2}})
+			err := p.Flow()
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, p.GetOutput()["a"])
+			ops, flows := p.GetRetries()
+			assert.Equal(t, tt.expectedRetries, ops)
+			assert.Equal(t, 0, flows)
+		})
+	}
+}
+
+func TestPipe_SetRetryPolicy_AggregateOp(t *testing.T) {
+	calls := 0
+	p := NewAggregateOpPipe("aggregate", func(values []float64) (float64, error) {
+		calls++
+		if calls == 1 {
+			return 0, errTransient
+		}
+		return float64(len(values)), nil
+	})
+	p.SetRetryPolicy(RetryPolicy{MaxAttempts: 2})
+	p.SetInput(map[string][]float64{"a": {1, 2, 3}})
+	assert.NoError(t, p.Flow())
+	assert.Equal(t, []float64{3}, p.GetOutput()["a"])
+	ops, _ := p.GetRetries()
+	assert.Equal(t, 1, ops)
+}
+
+func TestPipe_SetFlowRetryPolicy(t *testing.T) {
+	p := NewSingleOpsPipe("flow", []func(float64) (float64, error){failTimes(1, errTransient)})
+	p.SetFlowRetryPolicy(RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond})
+	p.SetInput(map[string][]float64{"a": {1, 2}})
+	assert.NoError(t, p.Flow())
+	assert.Equal(t, []float64{2, 4}, p.GetOutput()["a"])
+	ops, flows := p.GetRetries()
+	assert.Equal(t, 0, ops)
+	assert.Equal(t, 1, flows)
+}
+
+func TestPipe_SetFlowRetryPolicy_StopsWithContext(t *testing.T) {
+	p := NewSingleOpsPipe("flow", []func(float64) (float64, error){failTimes(1, errTransient)})
+	p.SetFlowRetryPolicy(RetryPolicy{MaxAttempts: 2, Backoff: time.Hour})
+	p.SetInput(map[string][]float64{"a": {1}})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := p.FlowContext(ctx)
+	assert.EqualError(t, err, "failed to apply op to val 1 on row 0 with op msg: transient")
+	_, flows := p.GetRetries()
+	assert.Equal(t, 0, flows)
+}
+
+func TestRetryPolicy_wait(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		policy   RetryPolicy
+		expected []time.Duration
+	}{
+		{
+			name:     "test_doubles_backoff",
+			policy:   RetryPolicy{Backoff: 10 * time.Millisecond},
+			expected: []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond},
+		},
+		{
+			name:     "test_bounds_backoff",
+			policy:   RetryPolicy{Backoff: 10 * time.Millisecond, MaxBackoff: 25 * time.Millisecond},
+			expected: []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 25 * time.Millisecond},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, expected := range tt.expected {
+				assert.Equal(t, expected, tt.policy.wait(i+1))
+			}
+		})
+	}
+
+	jittered := RetryPolicy{Backoff: 10 * time.Millisecond, Jitter: 0.5}
+	for i := 0; i < 10; i++ {
+		d := jittered.wait(1)
+		assert.True(t, d >= 5*time.Millisecond && d <= 10*time.Millisecond)
+	}
+}
@@ -0,0 +1,287 @@
+package pipe
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/flaviuvadan/pipe-flow/column"
+	"github.com/flaviuvadan/pipe-flow/progress"
+)
+
+// Record holds the values of a row of several named columns, null values are nil
+type Record map[string]interface{}
+
+// Float returns the value of the named column of the record as a float64, Int values are converted
+func (r Record) Float(name string) (float64, error) {
+	switch v := r[name].(type) {
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	case nil:
+		return 0, fmt.Errorf("value of column (%s) is null", name)
+	default:
+		return 0, fmt.Errorf("value %v of column (%s) is not numeric", v, name)
+	}
+}
+
+// RowOp is a context aware op over a row of several columns, it returns the values of the columns it derives from the
+// row. Derived columns that are missing from the returned record, or are nil, are null for that row
+type RowOp func(context.Context, Record) (Record, error)
+
+// NewRowPipe returns a new instance of Pipe whose op receives, for every row, a record of the given input columns and
+// returns a record of the given output columns, e.g revenue from price and qty. Input values keep their column type
+func NewRowPipe(ds string, in []string, out map[string]column.Type, op RowOp) *Pipe {
+	return &Pipe{
+		Description: ds,
+		rowOp:       op,
+		rowInputs:   in,
+		rowOutputs:  out,
+	}
+}
+
+// GetInputColumns returns the names of the input columns of a row pipe or a group by, nil for other pipes
+func (p *Pipe) GetInputColumns() []string {
+	return p.rowInputs
+}
+
+// GetOutputNames returns the names of the output columns of the pipe when it flows the given input columns, row pipes
+// return their output columns in alphabetical order, group bys their key column followed by their aggregate column and
+// multi aggregate pipes a column per aggregate of every input column
+func (p *Pipe) GetOutputNames(cols []string) []string {
+	if p.groupBy != nil {
+		return []string{p.groupBy.key, p.GetOutputName(p.groupBy.value)}
+	}
+	if p.rowOp != nil {
+		names := make([]string, 0, len(p.rowOutputs))
+		for name := range p.rowOutputs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names
+	}
+	if p.aggregates != nil && p.broadcastOp == nil {
+		names := make([]string, 0, len(cols)*len(p.aggregates))
+		for _, c := range cols {
+			for _, agg := range p.aggregates {
+				names = append(names, p.aggregateName(c, agg))
+			}
+		}
+		return names
+	}
+	names := make([]string, len(cols))
+	for i, c := range cols {
+		names[i] = p.GetOutputName(c)
+	}
+	return names
+}
+
+// checkRowOp checks that a row pipe declares the input columns its records are made of
+func (p *Pipe) checkRowOp() error {
+	if p.rowOp != nil && len(p.rowInputs) == 0 {
+		return p.errorf("has a row op without input columns")
+	}
+	return nil
+}
+
+// flowThroughRowOp does the work of the row op on the whole input of the pipeline
+func (p *Pipe) flowThroughRowOp(ctx context.Context) error {
+	out, err := p.flowRows(ctx, p.input, 0, p.rowCount(p.input))
+	if err != nil {
+		return err
+	}
+	p.output = out
+	return nil
+}
+
+// flowRows applies the row op to every row of the given input columns and returns the derived columns. The offset is
+// the index of the first row, e.g in a stream, and progress is only reported when the total is known
+func (p *Pipe) flowRows(ctx context.Context, in map[string]*column.Column, offset, total int) (map[string]*column.Column, error) {
+	for _, c := range p.rowInputs {
+		if _, ok := in[c]; !ok {
+			return nil, p.errorf("is missing input column (%s)", c)
+		}
+	}
+	rows, at := p.alignRows(in)
+	out := make(map[string]*column.Column, len(p.rowOutputs))
+	for name, t := range p.rowOutputs {
+		out[name] = column.New(name, t, make([]interface{}, 0, rows))
+	}
+	if total != progress.UnknownTotal {
+		p.report(0, total)
+	}
+	// kept holds the rows the records come from once row filters dropped some or the input had filtered rows already
+	var kept []int
+	if len(p.rowFilters) > 0 || p.errorPolicy.Action == ErrorSkip || p.errorPolicy.Action == ErrorDeadLetter {
+		kept = []int{}
+	}
+	for _, c := range p.rowInputs {
+		if in[c].Rows != nil {
+			kept = []int{}
+		}
+	}
+	for i := 0; i < rows; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, p.stopped(err)
+		}
+		rec := make(Record, len(p.rowInputs))
+		for _, c := range p.rowInputs {
+			j := i
+			if at != nil {
+				j = at[c][i]
+			}
+			if col := in[c]; j < col.Len() && !col.IsNull(j) {
+				rec[c] = col.Values[j]
+			} else {
+				rec[c] = nil
+			}
+		}
+		keep, err := p.keepRecord(ctx, rec, offset+i)
+		if err != nil {
+			return nil, err
+		}
+		if !keep {
+			continue
+		}
+		res, e, err := p.applyRowOp(ctx, rec, offset+i)
+		if err != nil {
+			if e == nil {
+				return nil, err
+			}
+			sub, keep, err := p.handleError(e, err)
+			if err != nil {
+				return nil, err
+			}
+			if !keep {
+				continue
+			}
+			res = Record{}
+			for name := range p.rowOutputs {
+				res[name] = sub
+			}
+		}
+		if kept != nil {
+			first := p.rowInputs[0]
+			if at != nil {
+				kept = append(kept, in[first].Row(at[first][i]))
+			} else {
+				kept = append(kept, in[first].Row(i))
+			}
+		}
+		for name, c := range out {
+			c.Push(res[name])
+		}
+		if total != progress.UnknownTotal && p.reporting != nil && (i+1)%p.reportingEvery == 0 && i+1 != total {
+			p.report(i+1, total)
+		}
+	}
+	if total != progress.UnknownTotal {
+		p.report(rows, total)
+	}
+	if kept != nil {
+		for _, c := range out {
+			c.Rows = kept
+		}
+	}
+	return out, nil
+}
+
+// applyRowOp applies the row op to the given record of the given row and checks the columns it derives, failed ops
+// return an OpError along with the error the pipe fails with
+func (p *Pipe) applyRowOp(ctx context.Context, rec Record, row int) (Record, *OpError, error) {
+	res, err := runOp(ctx, p, 0, row, func(ctx context.Context) (Record, error) {
+		return p.rowOp(ctx, rec)
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, nil, p.stoppedOn(ctx.Err(), 0, row)
+		}
+		return nil, &OpError{Row: row, Value: rec, Err: err}, fmt.Errorf("failed to apply row op on row %v with op msg: %w", row, err)
+	}
+	for k := range res {
+		if _, ok := p.rowOutputs[k]; !ok {
+			err := fmt.Errorf("undeclared output column (%s)", k)
+			return nil, &OpError{Row: row, Value: rec, Err: err}, fmt.Errorf("failed to apply row op on row %v, %v", row, err)
+		}
+	}
+	for name, t := range p.rowOutputs {
+		if v := res[name]; v != nil {
+			if err := t.Check(v); err != nil {
+				return nil, &OpError{Row: row, Value: rec, Err: err}, fmt.Errorf("failed to apply row op on row %v with op msg: %v", row, err)
+			}
+		}
+	}
+	return res, nil, nil
+}
+
+// alignRows aligns the given input columns of a row pipe on the rows their values come from, see column.Row, so that
+// every record holds values of the same row even when the inputs come from upstream pipes that dropped different rows.
+// Only the rows that are part of every input make a record. It returns the number of records along with, for every
+// input column, the index of the value of every record, nil when the inputs come from the same rows and are aligned by
+// position
+func (p *Pipe) alignRows(in map[string]*column.Column) (int, map[string][]int) {
+	first := in[p.rowInputs[0]]
+	aligned := true
+	for _, c := range p.rowInputs[1:] {
+		if (first.Rows != nil || in[c].Rows != nil) && !sameRows(first, in[c]) {
+			aligned = false
+			break
+		}
+	}
+	if aligned {
+		return p.rowCount(in), nil
+	}
+	// indices holds the index of every row in every input column
+	indices := make(map[string]map[int]int, len(p.rowInputs))
+	for _, c := range p.rowInputs {
+		indices[c] = make(map[int]int, in[c].Len())
+		for j := 0; j < in[c].Len(); j++ {
+			indices[c][in[c].Row(j)] = j
+		}
+	}
+	at := make(map[string][]int, len(p.rowInputs))
+	rows := 0
+	for j := 0; j < first.Len(); j++ {
+		row := first.Row(j)
+		found := true
+		for _, c := range p.rowInputs {
+			if _, ok := indices[c][row]; !ok {
+				found = false
+				break
+			}
+		}
+		if !found {
+			continue
+		}
+		for _, c := range p.rowInputs {
+			at[c] = append(at[c], indices[c][row])
+		}
+		rows++
+	}
+	return rows, at
+}
+
+// sameRows tells whether the values of the given columns come from the same rows, in the same order
+func sameRows(a, b *column.Column) bool {
+	if a.Len() != b.Len() {
+		return false
+	}
+	for i := 0; i < a.Len(); i++ {
+		if a.Row(i) != b.Row(i) {
+			return false
+		}
+	}
+	return true
+}
+
+// rowCount returns the number of rows of the longest of the given input columns of a row pipe
+func (p *Pipe) rowCount(in map[string]*column.Column) int {
+	rows := 0
+	for _, c := range p.rowInputs {
+		if col, ok := in[c]; ok && col.Len() > rows {
+			rows = col.Len()
+		}
+	}
+	return rows
+}
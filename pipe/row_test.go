@@ -0,0 +1,141 @@
+package pipe
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flaviuvadan/pipe-flow/column"
+)
+
+// revenue is a test row op that multiplies price by qty
+func revenue(_ context.Context, r Record) (Record, error) {
+	price, err := r.Float("price")
+	if err != nil {
+		return nil, err
+	}
+	qty, err := r.Float("qty")
+	if err != nil {
+		return nil, err
+	}
+	return Record{"revenue": price * qty, "big": price*qty > 10}, nil
+}
+
+func TestNewRowPipe_Flow(t *testing.T) {
+	t.Parallel()
+	outputs := map[string]column.Type{"revenue": column.Float, "big": column.Bool}
+	tests := []struct {
+		name        string
+		op          RowOp
+		price       []interface{}
+		expected    map[string]*column.Column
+		expectedErr error
+	}{
+		{
+			name:  "test_derives_columns_from_records",
+			op:    revenue,
+			price: []interface{}{1.5, 4.0},
+			expected: map[string]*column.Column{
+				"revenue": column.New("revenue", column.Float, []interface{}{3.0, 12.0}),
+				"big":     column.New("big", column.Bool, []interface{}{false, true}),
+			},
+		},
+		{
+			name: "test_missing_outputs_are_null",
+			op: func(_ context.Context, r Record) (Record, error) {
+				return Record{"revenue": r["price"]}, nil
+			},
+			price: []interface{}{1.5, 4.0},
+			expected: map[string]*column.Column{
+				"revenue": column.New("revenue", column.Float, []interface{}{1.5, 4.0}),
+				"big": func() *column.Column {
+					c := column.New("big", column.Bool, nil)
+					c.Push(nil)
+					c.Push(nil)
+					return c
+				}(),
+			},
+		},
+		{
+			name:        "test_returns_err_on_op_err",
+			op:          revenue,
+			price:       []interface{}{1.5, "x"},
+			expectedErr: fmt.Errorf("failed to apply row op on row 1 with op msg: value x of column (price) is not numeric"),
+		},
+		{
+			name: "test_returns_err_on_undeclared_output",
+			op: func(_ context.Context, r Record) (Record, error) {
+				return Record{"other": 1.0}, nil
+			},
+			price:       []interface{}{1.5, 4.0},
+			expectedErr: fmt.Errorf("failed to apply row op on row 0, undeclared output column (other)"),
+		},
+		{
+			name: "test_returns_err_on_output_of_wrong_type",
+			op: func(_ context.Context, r Record) (Record, error) {
+				return Record{"revenue": "x"}, nil
+			},
+			price:       []interface{}{1.5, 4.0},
+			expectedErr: fmt.Errorf("failed to apply row op on row 0 with op msg: value x of type string is not of type float64"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewRowPipe("test", []string{"price", "qty"}, outputs, tt.op)
+			p.SetTypedInput(map[string]*column.Column{
+				"price": column.New("price", column.String, tt.price),
+				"qty":   column.New("qty", column.Int, []interface{}{int64(2), int64(3)}),
+			})
+			err := p.Flow()
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, p.GetTypedOutput())
+			assert.Equal(t, []string{"big", "revenue"}, p.GetOutputNames(nil))
+		})
+	}
+}
+
+func TestNewRowPipe_FlowChunk(t *testing.T) {
+	p := NewRowPipe("test", []string{"price", "qty"}, map[string]column.Type{"revenue": column.Float, "big": column.Bool}, revenue)
+	assert.NoError(t, p.Begin())
+	out, err := p.FlowChunk(context.Background(), map[string]*column.Column{
+		"price": column.FromFloats("price", []float64{1, 2}),
+		"qty":   column.FromFloats("qty", []float64{3, 4}),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{3.0, 8.0}, out["revenue"].Values)
+	_, err = p.FlowChunk(context.Background(), map[string]*column.Column{
+		"price": column.New("price", column.String, []interface{}{"x"}),
+		"qty":   column.FromFloats("qty", []float64{1}),
+	})
+	assert.EqualError(t, err, "failed to apply row op on row 2 with op msg: value x of column (price) is not numeric")
+}
+
+func TestNewRowPipe_Flow_AlignsRows(t *testing.T) {
+	p := NewRowPipe("test", []string{"price", "qty"}, map[string]column.Type{"revenue": column.Float, "big": column.Bool}, revenue)
+	// upstream pipes dropped different rows: price lost row 1 and qty lost row 2
+	price := column.FromFloats("price", []float64{1, 3, 4})
+	price.Rows = []int{0, 2, 3}
+	qty := column.FromFloats("qty", []float64{10, 20, 40})
+	qty.Rows = []int{0, 1, 3}
+	p.SetTypedInput(map[string]*column.Column{"price": price, "qty": qty})
+	assert.NoError(t, p.Flow())
+	expected := column.FromFloats("revenue", []float64{10, 160})
+	expected.Rows = []int{0, 3}
+	assert.Equal(t, expected, p.GetTypedOutput()["revenue"])
+}
+
+func TestNewRowPipe_Flow_WithoutInputs(t *testing.T) {
+	t.Parallel()
+	for _, in := range [][]string{nil, {}} {
+		p := NewRowPipe("test", in, map[string]column.Type{"revenue": column.Float}, revenue)
+		p.SetTypedInput(map[string]*column.Column{"price": column.FromFloats("price", []float64{1})})
+		assert.EqualError(t, p.Flow(), "pipe (test) has a row op without input columns")
+		assert.EqualError(t, p.Begin(), "pipe (test) has a row op without input columns")
+	}
+}
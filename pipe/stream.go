@@ -0,0 +1,194 @@
+package pipe
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/flaviuvadan/pipe-flow/column"
+	"github.com/flaviuvadan/pipe-flow/progress"
+)
+
+// Begin prepares the pipe to receive chunks of a streaming source, see FlowChunk and End. The timeout of the pipe, if
+// any, bounds the whole stream from Begin on
+func (p *Pipe) Begin() error {
+	if err := p.checkOps(); err != nil {
+		return err
+	}
+	if p.aggregateOp != nil {
+		return p.errorf("cannot stream through an aggregate op, use an accumulator")
+	}
+	if p.groupBy != nil {
+		return p.errorf("cannot stream through a group by")
+	}
+	if p.broadcastOp != nil {
+		return p.errorf("cannot stream through a broadcast op, it needs the aggregates of the whole column")
+	}
+	if p.aggregates != nil {
+		return p.errorf("cannot stream through aggregate ops, use accumulators")
+	}
+	if err := p.checkErrorPolicy(); err != nil {
+		return err
+	}
+	if err := p.checkRetryPolicies(); err != nil {
+		return err
+	}
+	if err := p.closeDeadLetter(); err != nil {
+		return err
+	}
+	p.start = time.Now()
+	p.deadline = time.Time{}
+	if p.timeout > 0 {
+		p.deadline = p.start.Add(p.timeout)
+	}
+	p.useReporter(context.Background())
+	p.output = nil
+	p.streamed = 0
+	p.dropped = 0
+	p.errors = 0
+	p.rowsIn, p.rowsOut, p.nulls = 0, 0, 0
+	p.opRetries, p.flowRetries = 0, 0
+	p.accumulators = map[string]Accumulator{}
+	p.windowStates = map[string]*windowState{}
+	return nil
+}
+
+// FlowChunk flows a chunk of rows of a streaming source through the pipe. Single ops and row pipes return the chunk
+// they produce so it can be written right away, accumulator pipes and Tumbling or Sliding windows only accumulate the
+// chunk and return nil. Progress is reported once per chunk. The dead-letter file of the pipe, if any, is closed when the chunk fails
+func (p *Pipe) FlowChunk(ctx context.Context, chunk map[string]*column.Column) (out map[string]*column.Column, err error) {
+	ctx, cancel := p.withDeadline(ctx)
+	defer cancel()
+	p.useReporter(ctx)
+	defer func() {
+		if err != nil {
+			_ = p.closeDeadLetter()
+			return
+		}
+		p.count(chunk, out)
+	}()
+	if err := ctx.Err(); err != nil {
+		return nil, p.stopped(err)
+	}
+	if err := p.checkOutputNames(chunk); err != nil {
+		return nil, err
+	}
+	if p.rowOp != nil {
+		out, err := p.flowRows(ctx, chunk, p.streamed, progress.UnknownTotal)
+		if err != nil {
+			return nil, err
+		}
+		p.streamed += p.rowCount(chunk)
+		p.report(p.streamed, progress.UnknownTotal)
+		return out, nil
+	}
+	if p.window != nil {
+		return p.flowWindowChunk(ctx, chunk)
+	}
+	if p.singleOps != nil {
+		out = map[string]*column.Column{}
+	}
+	rows := 0
+	for col, c := range chunk {
+		c, err := c.As(p.inType)
+		if err != nil {
+			return nil, p.errorf("cannot flow input, err: %v", err)
+		}
+		if c.Len() > rows {
+			rows = c.Len()
+		}
+		c, err = p.filter(ctx, c, p.streamed)
+		if err != nil {
+			return nil, err
+		}
+		var acc Accumulator
+		if p.newAccumulator != nil {
+			if acc = p.accumulators[col]; acc == nil {
+				acc = p.newAccumulator()
+				p.accumulators[col] = acc
+			}
+		}
+		var o *column.Column
+		if p.singleOps != nil {
+			o = column.New(p.GetOutputName(col), p.outType, make([]interface{}, 0, c.Len()))
+			o.Rows = c.Rows
+			out[o.Name] = o
+		}
+		// kept holds the rows that are left once the error policy dropped failed ones
+		kept := make([]int, 0, c.Len())
+		for i, val := range c.Values {
+			if err := ctx.Err(); err != nil {
+				return nil, p.stopped(err)
+			}
+			row := p.streamed + c.Row(i)
+			switch {
+			case c.IsNull(i):
+				if p.singleOps != nil {
+					o.Push(nil)
+					kept = append(kept, i)
+				}
+			case p.singleOps != nil:
+				newVal, keep, err := p.flowValue(ctx, val, row)
+				if err != nil {
+					return nil, err
+				}
+				if keep {
+					o.Push(newVal)
+					kept = append(kept, i)
+				}
+			case acc != nil:
+				if err := acc.Add(val.(float64)); err != nil {
+					return nil, fmt.Errorf("failed to accumulate val %v on row %v of col (%v), err: %v", val, row, col, err)
+				}
+			}
+		}
+		if o != nil && len(kept) < c.Len() {
+			o.Rows = make([]int, len(kept))
+			for j, i := range kept {
+				o.Rows[j] = c.Row(i)
+			}
+		}
+	}
+	p.streamed += rows
+	p.report(p.streamed, progress.UnknownTotal)
+	return out, nil
+}
+
+// End finishes the stream of the pipe and returns the results of its accumulators or of its Tumbling and Sliding
+// windows, if any
+func (p *Pipe) End() (out map[string]*column.Column, err error) {
+	defer func() { p.end = time.Now() }()
+	defer func() {
+		if cerr := p.closeDeadLetter(); cerr != nil && err == nil {
+			out, err = nil, cerr
+		}
+		if err == nil {
+			p.count(nil, out)
+		}
+	}()
+	if p.window != nil {
+		ctx, cancel := p.withDeadline(context.Background())
+		defer cancel()
+		out, err := p.endWindow(ctx)
+		if err != nil {
+			return nil, err
+		}
+		p.report(p.streamed, p.streamed)
+		return out, nil
+	}
+	if p.newAccumulator == nil {
+		p.report(p.streamed, p.streamed)
+		return nil, nil
+	}
+	p.output = map[string]*column.Column{}
+	for col, acc := range p.accumulators {
+		val, err := acc.Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to perform aggregate op on col (%v), err: %v", col, err)
+		}
+		name := p.GetOutputName(col)
+		p.output[name] = column.New(name, column.Float, []interface{}{val})
+	}
+	p.report(p.streamed, p.streamed)
+	return p.output, nil
+}
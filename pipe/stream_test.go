@@ -0,0 +1,94 @@
+package pipe
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPipe_FlowChunk(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name           string
+		pipe           *Pipe
+		chunks         []map[string][]float64
+		expectedChunks []map[string][]float64
+		expectedEnd    map[string][]float64
+		expectedErr    error
+	}{
+		{
+			name: "test_single_ops_return_every_chunk",
+			pipe: NewSingleOpsPipe("single", []func(float64) (float64, error){
+				func(v float64) (float64, error) {
+					return v + 1, nil
+				},
+			}),
+			chunks: []map[string][]float64{
+				{"a": {1, 2}},
+				{"a": {3}},
+			},
+			expectedChunks: []map[string][]float64{
+				{"a": {2, 3}},
+				{"a": {4}},
+			},
+			expectedEnd: nil,
+		},
+		{
+			name: "test_single_ops_report_rows_across_chunks",
+			pipe: NewSingleOpsPipe("single", []func(float64) (float64, error){
+				func(v float64) (float64, error) {
+					if v > 2 {
+						return 0, fmt.Errorf("too large")
+					}
+					return v, nil
+				},
+			}),
+			chunks: []map[string][]float64{
+				{"a": {1, 2}},
+				{"a": {3}},
+			},
+			expectedChunks: []map[string][]float64{
+				{"a": {1, 2}},
+			},
+			expectedErr: fmt.Errorf("failed to apply op to val 3 on row 2 with op msg: too large"),
+		},
+		{
+			name: "test_accumulator_returns_result_on_end",
+			pipe: NewAccumulatorPipe("accumulator", newSumAccumulator),
+			chunks: []map[string][]float64{
+				{"a": {1, 2}},
+				{"a": {3}},
+			},
+			expectedChunks: []map[string][]float64{nil, nil},
+			expectedEnd: map[string][]float64{
+				"a": {6},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NoError(t, tt.pipe.Begin())
+			for i, c := range tt.chunks {
+				out, err := tt.pipe.FlowChunk(context.Background(), fromFloats(c))
+				if err != nil {
+					assert.EqualError(t, err, tt.expectedErr.Error())
+					return
+				}
+				assert.Equal(t, tt.expectedChunks[i], toFloats(out))
+			}
+			end, err := tt.pipe.End()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedEnd, toFloats(end))
+		})
+	}
+}
+
+func TestPipe_Begin(t *testing.T) {
+	t.Parallel()
+	p := NewAggregateOpPipe("aggregate", func(values []float64) (float64, error) {
+		return 0, nil
+	})
+	assert.EqualError(t, p.Begin(), "pipe (aggregate) cannot stream through an aggregate op, use an accumulator")
+}
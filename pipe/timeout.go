@@ -0,0 +1,141 @@
+package pipe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TimeoutError is the error of an op call that exceeded the op timeout of its pipe, see SetOpTimeout
+type TimeoutError struct {
+	Pipe    string        // Description of the pipe
+	Op      int           // index of the op that timed out
+	Row     int           // row the op was called on, the last row of a window, -1 for ops over a whole column or group
+	Timeout time.Duration // op timeout of the pipe
+}
+
+// Error returns the message of the error, the errors that wrap it name the row already
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("op %d of pipe (%s) exceeded its timeout of %v", e.Op, e.Pipe, e.Timeout)
+}
+
+// SetOpTimeout bounds every call of an op of the pipe, 0 means ops are not bounded. A call that exceeds it fails with a
+// TimeoutError, see the README for how calls that do not return are handled
+func (p *Pipe) SetOpTimeout(d time.Duration) {
+	p.opTimeout = d
+}
+
+// SetTimeout bounds the whole flow, or stream, of the pipe through its context, 0 means the pipe is not bounded
+func (p *Pipe) SetTimeout(d time.Duration) {
+	p.timeout = d
+}
+
+// withDeadline returns the given context bounded by the deadline of the pipe, if any, see SetTimeout
+func (p *Pipe) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.deadline.IsZero() {
+		return ctx, func() {}
+	}
+	return context.WithDeadline(ctx, p.deadline)
+}
+
+// timedOut tells whether the given context error comes from the pipe exceeding its own timeout
+func (p *Pipe) timedOut(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) && !p.deadline.IsZero() && !time.Now().Before(p.deadline)
+}
+
+// stoppedOn is like stopped for a pipe that was stopped while the given op ran on the given row
+func (p *Pipe) stoppedOn(err error, op, row int) error {
+	if p.timedOut(err) {
+		return p.errorf("exceeded its timeout of %v on op %d of row %d, err: %w", p.timeout, op, row, err)
+	}
+	return p.stopped(err)
+}
+
+// opResult holds the outcome of an op call that runs in the background
+type opResult[T any] struct {
+	val T
+	err error
+}
+
+// runOp calls the op of the pipe at index i on the given row until it succeeds or the op retry policy of the pipe gives
+// up, see callOp
+func runOp[T any](ctx context.Context, p *Pipe, i, row int, op func(context.Context) (T, error)) (T, error) {
+	val, retries, err := callOp(ctx, p, i, row, op)
+	p.opRetries += retries
+	return val, err
+}
+
+// callOp calls the op of the pipe at index i on the given row until it succeeds or the op retry policy of the pipe
+// gives up, and returns the number of retries
+func callOp[T any](ctx context.Context, p *Pipe, i, row int, op func(context.Context) (T, error)) (T, int, error) {
+	var val T
+	retries, err := p.opRetry.do(ctx, func() error {
+		if err := p.waitOp(ctx, i); err != nil {
+			return err
+		}
+		var running <-chan struct{}
+		var err error
+		val, running, err = timeOp(ctx, p, i, row, op)
+		p.runningMu.Lock()
+		defer p.runningMu.Unlock()
+		if p.running == nil {
+			p.running = map[int]<-chan struct{}{}
+		}
+		p.running[i] = running
+		return err
+	})
+	return val, retries, err
+}
+
+// waitOp waits for the last call of the op of the pipe at index i to return when it exceeded the op timeout, so that
+// the op never runs twice at once, or for the given context to be done
+func (p *Pipe) waitOp(ctx context.Context, i int) error {
+	p.runningMu.Lock()
+	running := p.running[i]
+	p.runningMu.Unlock()
+	if running == nil {
+		return nil
+	}
+	select {
+	case <-running:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// timeOp makes a single call of the op of the pipe at index i on the given row. When the pipe has an op timeout, the op
+// runs in the background so that the pipe does not wait on it once the op timeout is exceeded or the given context is
+// done, in which case the context error is returned. The returned channel is closed once an op that the pipe stopped
+// waiting on returns, it is nil when the op returned already
+func timeOp[T any](ctx context.Context, p *Pipe, i, row int, op func(context.Context) (T, error)) (T, <-chan struct{}, error) {
+	if p.opTimeout <= 0 {
+		val, err := op(ctx)
+		return val, nil, err
+	}
+	opCtx, cancel := context.WithTimeout(ctx, p.opTimeout)
+	defer cancel()
+	res := make(chan opResult[T], 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		val, err := op(opCtx)
+		res <- opResult[T]{val: val, err: err}
+	}()
+	timeout := &TimeoutError{Pipe: p.Description, Op: i, Row: row, Timeout: p.opTimeout}
+	var zero T
+	select {
+	case r := <-res:
+		if r.err != nil && ctx.Err() == nil && opCtx.Err() != nil {
+			// the op observed its context and gave up once its timeout was exceeded
+			return zero, nil, timeout
+		}
+		return r.val, nil, r.err
+	case <-opCtx.Done():
+		if err := ctx.Err(); err != nil {
+			return zero, done, err
+		}
+		return zero, done, timeout
+	}
+}
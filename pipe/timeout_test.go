@@ -0,0 +1,298 @@
+package pipe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flaviuvadan/pipe-flow/column"
+)
+
+// sleepOn returns a single op that sleeps for the given duration on the given value, without observing its context
+func sleepOn(block float64, d time.Duration) func(float64) (float64, error) {
+	return func(v float64) (float64, error) {
+		if v == block {
+			time.Sleep(d)
+		}
+		return v * 2, nil
+	}
+}
+
+// waitOn returns a context aware single op that waits on the given value until its context is done
+func waitOn(block float64) SingleOpContext {
+	return func(ctx context.Context, v float64) (float64, error) {
+		if v == block {
+			<-ctx.Done()
+			return 0, ctx.Err()
+		}
+		return v * 2, nil
+	}
+}
+
+func TestPipe_SetOpTimeout(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name           string
+		errorPolicy    ErrorPolicy
+		retryPolicy    RetryPolicy
+		expected       *column.Column
+		expectedErrors int
+		expectedErr    error
+	}{
+		{
+			name:        "test_fails_on_op_timeout",
+			expectedErr: fmt.Errorf("failed to apply op to val 2 on row 1 with op msg: op 0 of pipe (test_fails_on_op_timeout) exceeded its timeout of 10ms"),
+		},
+		{
+			name:        "test_skips_rows_on_op_timeout",
+			errorPolicy: ErrorPolicy{Action: ErrorSkip},
+			expected: func() *column.Column {
+				c := column.FromFloats("a", []float64{2, 6})
+				c.Rows = []int{0, 2}
+				return c
+			}(),
+			expectedErrors: 1,
+		},
+		{
+			name: "test_does_not_retry_timeouts_the_classifier_rejects",
+			retryPolicy: RetryPolicy{MaxAttempts: 2, Retryable: func(err error) bool {
+				var e *TimeoutError
+				return !errors.As(err, &e)
+			}},
+			expectedErr: fmt.Errorf("failed to apply op to val 2 on row 1 with op msg: op 0 of pipe (test_does_not_retry_timeouts_the_classifier_rejects) exceeded its timeout of 10ms"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewSingleOpsPipe(tt.name, []func(float64) (float64, error){sleepOn(2, 30*time.Millisecond)})
+			p.SetOpTimeout(10 * time.Millisecond)
+			p.SetErrorPolicy(tt.errorPolicy)
+			p.SetRetryPolicy(tt.retryPolicy)
+			p.SetInput(map[string][]float64{"a": {1, 2, 3}})
+			err := p.Flow()
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				var e *TimeoutError
+				assert.True(t, errors.As(err, &e))
+				assert.Equal(t, &TimeoutError{Pipe: tt.name, Op: 0, Row: 1, Timeout: 10 * time.Millisecond}, e)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, p.GetTypedOutput()["a"])
+			assert.Equal(t, tt.expectedErrors, p.GetErrors())
+		})
+	}
+}
+
+func TestPipe_SetOpTimeout_ObservesContext(t *testing.T) {
+	p := NewSingleOpsPipeContext("context", []SingleOpContext{
+		func(ctx context.Context, v float64) (float64, error) {
+			<-ctx.Done()
+			return 0, ctx.Err()
+		},
+	})
+	p.SetOpTimeout(10 * time.Millisecond)
+	p.SetInput(map[string][]float64{"a": {1}})
+	assert.EqualError(t, p.Flow(), "failed to apply op to val 1 on row 0 with op msg: op 0 of pipe (context) exceeded its timeout of 10ms")
+}
+
+func TestPipe_SetOpTimeout_RowPipe(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	p := NewRowPipe("slow_revenue", []string{"price", "qty"}, map[string]column.Type{"revenue": column.Float}, func(ctx context.Context, r Record) (Record, error) {
+		<-release
+		return Record{"revenue": r["price"].(float64) * r["qty"].(float64)}, nil
+	})
+	p.SetOpTimeout(10 * time.Millisecond)
+	p.SetErrorPolicy(ErrorPolicy{Action: ErrorSubstitute})
+	p.SetTypedInput(map[string]*column.Column{
+		"price": column.FromFloats("price", []float64{1}),
+		"qty":   column.FromFloats("qty", []float64{2}),
+	})
+	assert.NoError(t, p.Flow())
+	assert.True(t, p.GetTypedOutput()["revenue"].IsNull(0))
+	assert.Equal(t, 1, p.GetErrors())
+}
+
+func TestPipe_SetOpTimeout_WaitsForTimedOutCalls(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name          string
+		errorPolicy   ErrorPolicy
+		retryPolicy   RetryPolicy
+		rows          []float64
+		expectedCalls int
+		expectedErr   error
+	}{
+		{
+			name:          "test_retries_wait_for_timed_out_calls",
+			retryPolicy:   RetryPolicy{MaxAttempts: 3},
+			rows:          []float64{1},
+			expectedCalls: 3,
+			expectedErr:   fmt.Errorf("failed to apply op to val 1 on row 0 with op msg: op 0 of pipe (test_retries_wait_for_timed_out_calls) exceeded its timeout of 5ms"),
+		},
+		{
+			name:          "test_next_rows_wait_for_timed_out_calls",
+			errorPolicy:   ErrorPolicy{Action: ErrorSkip},
+			rows:          []float64{1, 2, 3, 4, 5},
+			expectedCalls: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			calls, running, overlaps := 0, 0, 0
+			p := NewSingleOpsPipe(tt.name, []func(float64) (float64, error){
+				func(v float64) (float64, error) {
+					mu.Lock()
+					calls++
+					running++
+					if running > 1 {
+						overlaps++
+					}
+					mu.Unlock()
+					time.Sleep(20 * time.Millisecond)
+					mu.Lock()
+					running--
+					mu.Unlock()
+					return v, nil
+				},
+			})
+			p.SetOpTimeout(5 * time.Millisecond)
+			p.SetErrorPolicy(tt.errorPolicy)
+			p.SetRetryPolicy(tt.retryPolicy)
+			p.SetInput(map[string][]float64{"a": tt.rows})
+			err := p.Flow()
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, tt.expectedCalls, calls)
+			assert.Equal(t, 0, overlaps)
+		})
+	}
+}
+
+func TestTimeoutError_Row(t *testing.T) {
+	t.Parallel()
+	slow := func(ctx context.Context) {
+		<-ctx.Done()
+	}
+	tests := []struct {
+		name        string
+		pipe        *Pipe
+		input       map[string]*column.Column
+		expectedRow int
+	}{
+		{
+			name: "test_single_op",
+			pipe: NewSingleOpsPipeContext("single", []SingleOpContext{waitOn(2)}),
+			input: map[string]*column.Column{
+				"a": column.FromFloats("a", []float64{1, 2}),
+			},
+			expectedRow: 1,
+		},
+		{
+			name: "test_row_op",
+			pipe: NewRowPipe("row", []string{"a"}, map[string]column.Type{"b": column.Float}, func(ctx context.Context, r Record) (Record, error) {
+				if r["a"].(float64) == 3 {
+					slow(ctx)
+					return nil, ctx.Err()
+				}
+				return Record{"b": r["a"]}, nil
+			}),
+			input: map[string]*column.Column{
+				"a": column.FromFloats("a", []float64{1, 2, 3}),
+			},
+			expectedRow: 2,
+		},
+		{
+			name: "test_window_op_names_the_last_row_of_the_window",
+			pipe: NewTumblingPipe("window", 2, func(values []float64) (float64, error) {
+				if values[0] == 3 {
+					time.Sleep(30 * time.Millisecond)
+				}
+				return values[0], nil
+			}),
+			input: map[string]*column.Column{
+				"a": column.FromFloats("a", []float64{1, 2, 3, 4}),
+			},
+			expectedRow: 3,
+		},
+		{
+			name: "test_aggregate_op_has_no_row",
+			pipe: NewMultiAggregatePipe("aggregate", Aggregate{Name: "slow", Op: func(values []float64) (float64, error) {
+				time.Sleep(30 * time.Millisecond)
+				return 0, nil
+			}}),
+			input: map[string]*column.Column{
+				"a": column.FromFloats("a", []float64{1}),
+			},
+			expectedRow: -1,
+		},
+		{
+			name: "test_aggregate_op_pipe_has_no_row",
+			pipe: NewAggregateOpPipe("aggregate_op", func(values []float64) (float64, error) {
+				time.Sleep(30 * time.Millisecond)
+				return 0, nil
+			}),
+			input: map[string]*column.Column{
+				"a": column.FromFloats("a", []float64{1}),
+			},
+			expectedRow: -1,
+		},
+		{
+			name: "test_group_by_op_has_no_row",
+			pipe: NewGroupByPipe("group_by", "k", "a", func(values []float64) (float64, error) {
+				time.Sleep(30 * time.Millisecond)
+				return 0, nil
+			}),
+			input: map[string]*column.Column{
+				"k": column.FromFloats("k", []float64{1}),
+				"a": column.FromFloats("a", []float64{1}),
+			},
+			expectedRow: -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.pipe.SetOpTimeout(10 * time.Millisecond)
+			tt.pipe.SetTypedInput(tt.input)
+			err := tt.pipe.Flow()
+			var e *TimeoutError
+			if assert.True(t, errors.As(err, &e), "%v", err) {
+				assert.Equal(t, tt.expectedRow, e.Row)
+			}
+		})
+	}
+}
+
+func TestPipe_SetTimeout(t *testing.T) {
+	p := NewSingleOpsPipeContext("slow", []SingleOpContext{waitOn(1)})
+	p.SetTimeout(10 * time.Millisecond)
+	// the timeout of the pipe is not subject to its error policy
+	p.SetErrorPolicy(ErrorPolicy{Action: ErrorSkip})
+	p.SetInput(map[string][]float64{"a": {1, 2}})
+	err := p.Flow()
+	assert.EqualError(t, err, "pipe (slow) exceeded its timeout of 10ms on op 0 of row 0, err: context deadline exceeded")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestPipe_SetTimeout_Stream(t *testing.T) {
+	p := NewSingleOpsPipeContext("slow", []SingleOpContext{waitOn(3)})
+	p.SetTimeout(10 * time.Millisecond)
+	assert.NoError(t, p.Begin())
+	out, err := p.FlowChunk(context.Background(), fromFloats(map[string][]float64{"a": {1, 2}}))
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{2.0, 4.0}, out["a"].Values)
+	_, err = p.FlowChunk(context.Background(), fromFloats(map[string][]float64{"a": {3}}))
+	assert.EqualError(t, err, "pipe (slow) exceeded its timeout of 10ms on op 0 of row 2, err: context deadline exceeded")
+}
@@ -0,0 +1,329 @@
+package pipe
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flaviuvadan/pipe-flow/column"
+	"github.com/flaviuvadan/pipe-flow/progress"
+	"github.com/flaviuvadan/pipe-flow/stats"
+)
+
+// WindowKind tells how a window pipe groups the rows of a column
+type WindowKind int
+
+const (
+	Rolling    WindowKind = iota // one output per row, over the row and the size - 1 rows before it
+	Tumbling                     // one output per window of size consecutive rows, windows do not overlap
+	Sliding                      // one output per window of size consecutive rows, a window starts every step rows
+	Cumulative                   // one output per row, over the row and all the rows before it
+)
+
+// String returns the name of the window kind
+func (k WindowKind) String() string {
+	switch k {
+	case Rolling:
+		return "rolling"
+	case Tumbling:
+		return "tumbling"
+	case Sliding:
+		return "sliding"
+	case Cumulative:
+		return "cumulative"
+	}
+	return fmt.Sprintf("WindowKind(%d)", int(k))
+}
+
+// window holds the configuration of a window pipe
+type window struct {
+	kind           WindowKind
+	size           int                              // number of rows of a window, not used by Cumulative windows
+	step           int                              // number of rows between the start of two Sliding windows
+	op             func([]float64) (float64, error) // aggregates the valid values of a window
+	newAccumulator func() Accumulator               // accumulates the values of Cumulative windows
+}
+
+// windowState holds the rows of a column a window pipe has seen so far and did not output yet
+type windowState struct {
+	buf   []interface{} // values of the rows of the current window, nil for nulls
+	seen  int           // number of rows of the column seen so far
+	acc   Accumulator   // accumulator of a Cumulative window
+	added bool          // whether acc received any value
+}
+
+// NewRollingPipe returns a new instance of Pipe that outputs, for every row, the aggregate of the row and the size - 1
+// rows before it, e.g a rolling mean with stats.Mean. The first size - 1 rows are null as their window is not full
+func NewRollingPipe(ds string, size int, op func([]float64) (float64, error)) *Pipe {
+	return newWindowPipe(ds, &window{kind: Rolling, size: size, step: 1, op: op})
+}
+
+// NewTumblingPipe returns a new instance of Pipe that outputs the aggregate of every window of size consecutive rows,
+// the last window may hold fewer rows
+func NewTumblingPipe(ds string, size int, op func([]float64) (float64, error)) *Pipe {
+	return newWindowPipe(ds, &window{kind: Tumbling, size: size, step: size, op: op})
+}
+
+// NewSlidingPipe returns a new instance of Pipe that outputs the aggregate of windows of size consecutive rows that
+// start every step rows, only full windows are aggregated
+func NewSlidingPipe(ds string, size, step int, op func([]float64) (float64, error)) *Pipe {
+	return newWindowPipe(ds, &window{kind: Sliding, size: size, step: step, op: op})
+}
+
+// NewCumulativePipe returns a new instance of Pipe that outputs, for every row, the result of an accumulator created
+// by na that received the row and all the rows before it
+func NewCumulativePipe(ds string, na func() Accumulator) *Pipe {
+	return newWindowPipe(ds, &window{kind: Cumulative, newAccumulator: na})
+}
+
+// NewCumulativeSumPipe returns a new instance of Pipe that outputs the cumulative sum of its columns
+func NewCumulativeSumPipe(ds string) *Pipe {
+	return NewCumulativePipe(ds, func() Accumulator {
+		return &stats.SumAccumulator{}
+	})
+}
+
+// newWindowPipe returns a new instance of Pipe that flows its columns through the given window
+func newWindowPipe(ds string, w *window) *Pipe {
+	return &Pipe{
+		Description: ds,
+		inType:      column.Float,
+		outType:     column.Float,
+		window:      w,
+	}
+}
+
+// Windows tells whether the pipe flows its columns through a window, see NewRollingPipe and its siblings
+func (p *Pipe) Windows() bool {
+	return p.window != nil
+}
+
+// perWindow tells whether the pipe outputs a value per window rather than a value per row
+func (p *Pipe) perWindow() bool {
+	return p.window != nil && (p.window.kind == Tumbling || p.window.kind == Sliding)
+}
+
+// checkWindow checks that the window of the pipe, if any, can be flowed
+func (p *Pipe) checkWindow() error {
+	w := p.window
+	if w == nil {
+		return nil
+	}
+	if w.kind == Cumulative {
+		if w.newAccumulator == nil {
+			return p.errorf("has a cumulative window without an accumulator")
+		}
+		return nil
+	}
+	if w.op == nil {
+		return p.errorf("has a %v window without an op", w.kind)
+	}
+	if w.size < 1 {
+		return p.errorf("has a %v window of %d rows, windows need at least one row", w.kind, w.size)
+	}
+	if w.step < 1 {
+		return p.errorf("has a %v window that starts every %d rows, windows need a step of at least one row", w.kind, w.step)
+	}
+	return nil
+}
+
+// flowThroughWindow does the work of the window on the whole input of the pipeline
+func (p *Pipe) flowThroughWindow(ctx context.Context) error {
+	total := p.totalRows()
+	processed := 0
+	p.report(processed, total)
+	for _, col := range p.inputColumns() {
+		in, err := p.filter(ctx, p.input[col], 0)
+		if err != nil {
+			return err
+		}
+		st := p.newWindowState()
+		out, err := p.slideColumn(ctx, st, p.GetOutputName(col), in, 0)
+		if err != nil {
+			return err
+		}
+		if err := p.closeWindow(ctx, st, out, p.input[col].Len()); err != nil {
+			return err
+		}
+		p.output[out.Name] = out
+		processed += p.input[col].Len()
+		p.report(processed, total)
+	}
+	return nil
+}
+
+// newWindowState returns the state of a column that starts flowing through the window of the pipe
+func (p *Pipe) newWindowState() *windowState {
+	st := &windowState{}
+	if p.window.kind == Cumulative {
+		st.acc = p.window.newAccumulator()
+	}
+	return st
+}
+
+// slideColumn slides the window of the pipe over the given column, whose first row is the given offset, and returns the
+// outputs of the rows or windows it completed
+func (p *Pipe) slideColumn(ctx context.Context, st *windowState, name string, in *column.Column, offset int) (*column.Column, error) {
+	out := column.New(name, column.Float, make([]interface{}, 0, in.Len()))
+	if !p.perWindow() {
+		out.Rows = in.Rows
+	}
+	for i, val := range in.Values {
+		if err := ctx.Err(); err != nil {
+			return nil, p.stopped(err)
+		}
+		if in.IsNull(i) {
+			val = nil
+		}
+		res, emit, err := p.slide(ctx, st, val, offset+in.Row(i))
+		if err != nil {
+			return nil, err
+		}
+		if emit {
+			out.Push(res)
+		}
+	}
+	return out, nil
+}
+
+// slide adds the value of the given row to the window and tells whether it completed an output, which is nil when it
+// is null. Null values count as rows of the window but are not part of its aggregate
+func (p *Pipe) slide(ctx context.Context, st *windowState, val interface{}, row int) (interface{}, bool, error) {
+	w := p.window
+	st.seen++
+	if w.kind == Cumulative {
+		if val != nil {
+			if err := st.acc.Add(val.(float64)); err != nil {
+				return nil, false, fmt.Errorf("failed to accumulate val %v on row %v, err: %v", val, row, err)
+			}
+			st.added = true
+		}
+		if !st.added {
+			return nil, true, nil
+		}
+		res, err := st.acc.Result()
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to apply window op on row %v with op msg: %v", row, err)
+		}
+		return res, true, nil
+	}
+	st.buf = append(st.buf, val)
+	if len(st.buf) > w.size {
+		st.buf = st.buf[len(st.buf)-w.size:]
+	}
+	switch w.kind {
+	case Rolling:
+		if st.seen < w.size {
+			return nil, true, nil
+		}
+	case Tumbling:
+		if len(st.buf) < w.size {
+			return nil, false, nil
+		}
+		defer func() { st.buf = nil }()
+	case Sliding:
+		if st.seen < w.size || (st.seen-w.size)%w.step != 0 {
+			return nil, false, nil
+		}
+	}
+	res, err := p.aggregateWindow(ctx, st.buf, row)
+	return res, true, err
+}
+
+// closeWindow outputs the last window of a column once all its rows were seen, i.e the rows of a Tumbling window that
+// is not full. The given number of rows is the number of rows of the column
+func (p *Pipe) closeWindow(ctx context.Context, st *windowState, out *column.Column, rows int) error {
+	if p.window.kind != Tumbling || len(st.buf) == 0 {
+		return nil
+	}
+	res, err := p.aggregateWindow(ctx, st.buf, rows-1)
+	if err != nil {
+		return err
+	}
+	st.buf = nil
+	out.Push(res)
+	return nil
+}
+
+// aggregateWindow applies the window op to the valid values of the given window that ends on the given row, a window
+// without valid values is null
+func (p *Pipe) aggregateWindow(ctx context.Context, buf []interface{}, row int) (interface{}, error) {
+	values := make([]float64, 0, len(buf))
+	for _, v := range buf {
+		if v != nil {
+			values = append(values, v.(float64))
+		}
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+	res, err := runOp(ctx, p, 0, row, func(context.Context) (float64, error) {
+		return p.window.op(values)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply window op to the window ending on row %v with op msg: %w", row, err)
+	}
+	return res, nil
+}
+
+// flowWindowChunk flows a chunk of a streaming source through the window of the pipe, the rows of a window that is not
+// complete yet are kept until the next chunk. Tumbling and Sliding windows keep their outputs until the stream ends, like
+// accumulators, so that they are laid out as when the pipe flows a whole source, see endWindow
+func (p *Pipe) flowWindowChunk(ctx context.Context, chunk map[string]*column.Column) (map[string]*column.Column, error) {
+	out := map[string]*column.Column{}
+	rows := 0
+	for col, c := range chunk {
+		c, err := c.As(p.inType)
+		if err != nil {
+			return nil, p.errorf("cannot flow input, err: %v", err)
+		}
+		if c.Len() > rows {
+			rows = c.Len()
+		}
+		c, err = p.filter(ctx, c, p.streamed)
+		if err != nil {
+			return nil, err
+		}
+		st, ok := p.windowStates[col]
+		if !ok {
+			st = p.newWindowState()
+			p.windowStates[col] = st
+		}
+		o, err := p.slideColumn(ctx, st, p.GetOutputName(col), c, p.streamed)
+		if err != nil {
+			return nil, err
+		}
+		if !p.perWindow() {
+			out[o.Name] = o
+			continue
+		}
+		if p.output == nil {
+			p.output = map[string]*column.Column{}
+		}
+		if held, ok := p.output[o.Name]; ok {
+			held.Append(o)
+		} else {
+			p.output[o.Name] = o
+		}
+	}
+	p.streamed += rows
+	p.report(p.streamed, progress.UnknownTotal)
+	if p.perWindow() {
+		return nil, nil
+	}
+	return out, nil
+}
+
+// endWindow returns the outputs of the Tumbling and Sliding windows of the streamed columns, the last Tumbling window
+// included, see closeWindow. Other windows output every chunk as it flows and return nil
+func (p *Pipe) endWindow(ctx context.Context) (map[string]*column.Column, error) {
+	if !p.perWindow() {
+		return nil, nil
+	}
+	// every column that flowed a chunk has its output held already
+	for col, st := range p.windowStates {
+		if err := p.closeWindow(ctx, st, p.output[p.GetOutputName(col)], p.streamed); err != nil {
+			return nil, err
+		}
+	}
+	return p.output, nil
+}
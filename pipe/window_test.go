@@ -0,0 +1,202 @@
+package pipe
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flaviuvadan/pipe-flow/column"
+	"github.com/flaviuvadan/pipe-flow/stats"
+)
+
+// floats returns a Float column of the given values, nil values are null
+func floats(name string, values ...interface{}) *column.Column {
+	c := column.New(name, column.Float, nil)
+	for _, v := range values {
+		c.Push(v)
+	}
+	return c
+}
+
+func TestNewWindowPipes_Flow(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		pipe        *Pipe
+		pipeIn      *column.Column
+		expected    *column.Column
+		expectedErr error
+	}{
+		{
+			name:     "test_rolling_mean_is_null_until_window_is_full",
+			pipe:     NewRollingPipe("rolling", 3, stats.Mean),
+			pipeIn:   floats("a", 1.0, 2.0, 3.0, 4.0, 8.0),
+			expected: floats("a", nil, nil, 2.0, 3.0, 5.0),
+		},
+		{
+			name:     "test_rolling_skips_nulls",
+			pipe:     NewRollingPipe("rolling", 2, stats.Sum),
+			pipeIn:   floats("a", 1.0, nil, nil, 4.0),
+			expected: floats("a", nil, 1.0, nil, 4.0),
+		},
+		{
+			name:     "test_rolling_min",
+			pipe:     NewRollingPipe("rolling", 2, stats.Min),
+			pipeIn:   floats("a", 3.0, 1.0, 2.0),
+			expected: floats("a", nil, 1.0, 1.0),
+		},
+		{
+			name:     "test_tumbling_outputs_last_partial_window",
+			pipe:     NewTumblingPipe("tumbling", 2, stats.Max),
+			pipeIn:   floats("a", 1.0, 3.0, 2.0, 0.0, 5.0),
+			expected: floats("a", 3.0, 2.0, 5.0),
+		},
+		{
+			name:     "test_sliding_outputs_full_windows",
+			pipe:     NewSlidingPipe("sliding", 3, 2, stats.Sum),
+			pipeIn:   floats("a", 1.0, 2.0, 3.0, 4.0, 5.0, 6.0),
+			expected: floats("a", 6.0, 12.0),
+		},
+		{
+			name:     "test_cumulative_sum",
+			pipe:     NewCumulativeSumPipe("cumulative"),
+			pipeIn:   floats("a", nil, 1.0, 2.0, nil, 3.0),
+			expected: floats("a", nil, 1.0, 3.0, 3.0, 6.0),
+		},
+		{
+			name:     "test_cumulative_accumulator",
+			pipe:     NewCumulativePipe("cumulative", newSumAccumulator),
+			pipeIn:   floats("a", 1.0, 2.0),
+			expected: floats("a", 1.0, 3.0),
+		},
+		{
+			name: "test_returns_err_on_window_op_err",
+			pipe: NewTumblingPipe("tumbling", 2, func(values []float64) (float64, error) {
+				return 0, fmt.Errorf("test error")
+			}),
+			pipeIn:      floats("a", 1.0, 2.0),
+			expectedErr: fmt.Errorf("failed to apply window op to the window ending on row 1 with op msg: test error"),
+		},
+		{
+			name:        "test_returns_err_on_accumulator_err",
+			pipe:        NewCumulativePipe("cumulative", newSumAccumulator),
+			pipeIn:      floats("a", 1.0, -2.0),
+			expectedErr: fmt.Errorf("failed to accumulate val -2 on row 1, err: negative value"),
+		},
+		{
+			name:        "test_returns_err_on_empty_window",
+			pipe:        NewRollingPipe("rolling", 0, stats.Sum),
+			pipeIn:      floats("a", 1.0),
+			expectedErr: fmt.Errorf("pipe (rolling) has a rolling window of 0 rows, windows need at least one row"),
+		},
+		{
+			name:        "test_returns_err_on_empty_step",
+			pipe:        NewSlidingPipe("sliding", 2, 0, stats.Sum),
+			pipeIn:      floats("a", 1.0),
+			expectedErr: fmt.Errorf("pipe (sliding) has a sliding window that starts every 0 rows, windows need a step of at least one row"),
+		},
+		{
+			name:        "test_returns_err_on_nil_op",
+			pipe:        NewTumblingPipe("tumbling", 2, nil),
+			pipeIn:      floats("a", 1.0),
+			expectedErr: fmt.Errorf("pipe (tumbling) has a tumbling window without an op"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.pipe.SetTypedInput(map[string]*column.Column{"a": tt.pipeIn})
+			err := tt.pipe.Flow()
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, tt.pipe.GetTypedOutput()["a"])
+		})
+	}
+}
+
+func TestNewWindowPipes_FlowChunk(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name           string
+		pipe           *Pipe
+		chunks         []map[string][]float64
+		expectedChunks [][]interface{}
+		expectedEnd    map[string][]float64
+	}{
+		{
+			name: "test_rolling_keeps_rows_across_chunks",
+			pipe: NewRollingPipe("rolling", 2, stats.Sum),
+			chunks: []map[string][]float64{
+				{"a": {1, 2}},
+				{"a": {3}},
+			},
+			expectedChunks: [][]interface{}{{nil, 3.0}, {5.0}},
+		},
+		{
+			name: "test_tumbling_outputs_last_window_on_end",
+			pipe: NewTumblingPipe("tumbling", 2, stats.Sum),
+			chunks: []map[string][]float64{
+				{"a": {1}},
+				{"a": {2, 3}},
+			},
+			expectedChunks: [][]interface{}{{}, {}},
+			expectedEnd:    map[string][]float64{"a": {3, 3}},
+		},
+		{
+			name: "test_sliding_outputs_windows_on_end",
+			pipe: NewSlidingPipe("sliding", 2, 1, stats.Sum),
+			chunks: []map[string][]float64{
+				{"a": {1, 2}},
+				{"a": {3}},
+			},
+			expectedChunks: [][]interface{}{{}, {}},
+			expectedEnd:    map[string][]float64{"a": {3, 5}},
+		},
+		{
+			name: "test_cumulative_sum_across_chunks",
+			pipe: NewCumulativeSumPipe("cumulative"),
+			chunks: []map[string][]float64{
+				{"a": {1, 2}},
+				{"a": {3}},
+			},
+			expectedChunks: [][]interface{}{{1.0, 3.0}, {6.0}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NoError(t, tt.pipe.Begin())
+			for i, c := range tt.chunks {
+				out, err := tt.pipe.FlowChunk(context.Background(), fromFloats(c))
+				assert.NoError(t, err)
+				got := []interface{}{}
+				if o, ok := out["a"]; ok {
+					for j, v := range o.Values {
+						if o.IsNull(j) {
+							v = nil
+						}
+						got = append(got, v)
+					}
+				}
+				assert.Equal(t, tt.expectedChunks[i], got)
+			}
+			end, err := tt.pipe.End()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedEnd, toFloats(end))
+		})
+	}
+}
+
+func TestNewRollingPipe_Filters(t *testing.T) {
+	p := NewRollingPipe("rolling", 2, stats.Sum)
+	p.SetFilters(positive)
+	p.SetInput(map[string][]float64{"a": {1, -1, 2, 3}})
+	assert.NoError(t, p.Flow())
+	out := p.GetTypedOutput()["a"]
+	assert.Equal(t, []int{0, 2, 3}, out.Rows)
+	assert.Equal(t, []interface{}{3.0, 5.0}, out.ValidValues())
+	assert.Equal(t, 1, p.GetDropped())
+}
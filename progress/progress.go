@@ -0,0 +1,106 @@
+// progress package is responsible for holding the logic of reporting how far pipes got in processing their input
+package progress
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultWidth = 30 // number of characters used by the bar of a BarReporter when none is specified
+	UnknownTotal = -1 // total number of rows reported by pipes that stream and cannot know their total in advance
+)
+
+// Progress is a snapshot of how far a pipe got in processing its input
+type Progress struct {
+	Description string        // the Description of the pipe that reports progress
+	Processed   int           // number of rows processed so far
+	Total       int           // total number of rows the pipe has to process, UnknownTotal when streaming
+	Elapsed     time.Duration // time elapsed since the pipe started to flow
+}
+
+// Done tells whether the pipe processed all of its rows
+func (p Progress) Done() bool {
+	return p.Total != UnknownTotal && p.Processed >= p.Total
+}
+
+// Reporter is implemented by anything that wants to receive progress from pipes. Pipes may flow in parallel so
+// reporters must be safe for concurrent use
+type Reporter interface {
+	Report(p Progress)
+}
+
+// BarReporter writes a terminal progress bar line for every progress it receives
+type BarReporter struct {
+	w     io.Writer  // where the progress bars are written to
+	width int        // number of characters of the bar
+	mu    sync.Mutex // guards writes to w as pipes report concurrently
+}
+
+// NewBarReporter returns a new instance of a BarReporter that writes to w with a bar of the given width, a width that
+// is not positive means DefaultWidth
+func NewBarReporter(w io.Writer, width int) *BarReporter {
+	if width <= 0 {
+		width = DefaultWidth
+	}
+	return &BarReporter{
+		w:     w,
+		width: width,
+	}
+}
+
+// Report writes a progress bar line for the given progress
+func (b *BarReporter) Report(p Progress) {
+	filled := b.width
+	if p.Total > 0 {
+		filled = b.width * p.Processed / p.Total
+	}
+	if filled > b.width {
+		filled = b.width
+	}
+	total := strconv.Itoa(p.Total)
+	if p.Total == UnknownTotal {
+		filled = 0
+		total = "?"
+	}
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", b.width-filled)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, _ = fmt.Fprintf(b.w, "%s [%s] %d/%s (%v)\n", p.Description, bar, p.Processed, total, p.Elapsed.Round(time.Millisecond))
+}
+
+// ChanReporter forwards every progress it receives to a channel so it can be consumed by other services
+type ChanReporter struct {
+	updates chan Progress // channel progress is sent to
+}
+
+// NewChanReporter returns a new instance of a ChanReporter whose channel has the given buffer size. Reports are
+// dropped when the buffer is full, so that pipes never wait on a channel nobody reads, and the channel has to be
+// drained while pipes flow to receive all of them
+func NewChanReporter(buffer int) *ChanReporter {
+	return &ChanReporter{
+		updates: make(chan Progress, buffer),
+	}
+}
+
+// Report sends the given progress to the channel, or drops it when the buffer of the channel is full
+func (c *ChanReporter) Report(p Progress) {
+	select {
+	case c.updates <- p:
+	default:
+	}
+}
+
+// Updates returns the channel progress is sent to
+func (c *ChanReporter) Updates() <-chan Progress {
+	return c.updates
+}
+
+// Close closes the channel of updates, it should only be called once no more pipes report to it
+func (c *ChanReporter) Close() {
+	close(c.updates)
+}
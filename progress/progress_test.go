@@ -0,0 +1,83 @@
+package progress
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBarReporter_Report(t *testing.T) {
+	tests := []struct {
+		name     string
+		width    int
+		progress Progress
+		expected string
+	}{
+		{
+			name:     "test_reports_empty_bar",
+			width:    4,
+			progress: Progress{Description: "a", Processed: 0, Total: 4, Elapsed: time.Second},
+			expected: "a [    ] 0/4 (1s)\n",
+		},
+		{
+			name:     "test_reports_half_bar",
+			width:    4,
+			progress: Progress{Description: "a", Processed: 2, Total: 4, Elapsed: time.Second},
+			expected: "a [==  ] 2/4 (1s)\n",
+		},
+		{
+			name:     "test_reports_full_bar_on_empty_total",
+			width:    4,
+			progress: Progress{Description: "a", Processed: 0, Total: 0, Elapsed: time.Second},
+			expected: "a [====] 0/0 (1s)\n",
+		},
+		{
+			name:     "test_reports_unknown_total",
+			width:    4,
+			progress: Progress{Description: "a", Processed: 3, Total: UnknownTotal, Elapsed: time.Second},
+			expected: "a [    ] 3/? (1s)\n",
+		},
+		{
+			name:     "test_uses_default_width",
+			width:    0,
+			progress: Progress{Description: "a", Processed: 1, Total: 1, Elapsed: time.Second},
+			expected: "a [==============================] 1/1 (1s)\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			NewBarReporter(&b, tt.width).Report(tt.progress)
+			assert.Equal(t, tt.expected, b.String())
+		})
+	}
+}
+
+func TestChanReporter_Report(t *testing.T) {
+	c := NewChanReporter(2)
+	c.Report(Progress{Description: "a", Processed: 1, Total: 2})
+	c.Report(Progress{Description: "a", Processed: 2, Total: 2})
+	c.Close()
+	var got []Progress
+	for p := range c.Updates() {
+		got = append(got, p)
+	}
+	assert.Len(t, got, 2)
+	assert.False(t, got[0].Done())
+	assert.True(t, got[1].Done())
+}
+
+func TestChanReporter_Report_DropsWhenFull(t *testing.T) {
+	c := NewChanReporter(1)
+	c.Report(Progress{Description: "a", Processed: 1, Total: 2})
+	// nobody reads the channel, the report is dropped instead of blocking
+	c.Report(Progress{Description: "a", Processed: 2, Total: 2})
+	c.Close()
+	var got []Progress
+	for p := range c.Updates() {
+		got = append(got, p)
+	}
+	assert.Equal(t, []Progress{{Description: "a", Processed: 1, Total: 2}}, got)
+}
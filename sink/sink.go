@@ -6,22 +6,53 @@ import (
 	"fmt"
 	"os"
 	"path"
-	"strconv"
+	"sort"
 
+	"github.com/flaviuvadan/pipe-flow/column"
 	"github.com/flaviuvadan/pipe-flow/pipe"
 )
 
 const (
 	Precision    = 3  // number of float decimals
 	FloatBitSize = 64 // bit size of floats
+
+	DefaultFailed = "#FAILED" // default value written for the annotated columns of pipes that failed
+)
+
+// Layout tells how the sink lays out the collected columns in the dumped CSV file
+type Layout int
+
+const (
+	// RowLayout writes a header row with the column names followed by one record per row, this is the recommended
+	// layout as the result can be read back by a source or by any spreadsheet tool
+	RowLayout Layout = iota
+	// TransposedLayout writes every column as a CSV row made of the column name followed by its values
+	TransposedLayout
 )
 
 // Sink struct represents the final state of the whole plumbing system
 // if the filename was not specified, i.e it is "", results.csv is assumed
 type Sink struct {
-	filename string               // the name of the file the sink should dump data into
-	Pipes    []*pipe.Pipe         // the collection of Pipes whose values are incoming to the sink
-	data     map[string][]float64 // the data the sink collects from the Pipes to output to a CSV
+	filename string                    // the name of the file the sink should dump data into
+	Pipes    []*pipe.Pipe              // the collection of Pipes whose values are incoming to the sink
+	Layout   Layout                    // how columns are laid out in the dumped file, RowLayout by default
+	Fill     string                    // value used to pad columns shorter than others, e.g aggregates, in RowLayout
+	Null     string                    // value written for null values
+	Failed   string                    // value written for the annotated columns of pipes that failed, see Annotate
+	order    []string                  // explicit order of the output columns, columns not part of it follow in pipe order
+	data     map[string]*column.Column // the data the sink collects from the Pipes to output to a CSV
+	extra    map[string]*column.Column // columns that did not flow through any pipe, e.g passed through by a source
+	cols     []string                  // names of the collected columns in the order of Pipes
+	rowWise  map[string]bool           // collected columns that hold a value per row, i.e not aggregates
+	omitted  map[*pipe.Pipe]bool       // pipes whose output is left out of the collected columns, see Omit
+	failed   map[string]bool           // columns written with the Failed value on every row, see Annotate
+	file     *os.File                  // file rows are written to as they arrive from a streaming source
+	writer   *csv.Writer               // CSV writer of file
+	header   []string                  // columns of the rows that are written to file
+	written  []string                  // columns that were last written to file
+	rows     int                       // number of records that were last written to file, the header excluded
+	path     string                    // path of the file that was last written to
+	bytes    int64                     // number of bytes that were last written to file
 }
 
 // New returns a new instance of a Sink
@@ -35,31 +66,125 @@ func NewSink(fn string, p []*pipe.Pipe) (*Sink, error) {
 	s := &Sink{
 		filename: fn,
 		Pipes:    p,
+		Failed:   DefaultFailed,
 	}
 	return s, nil
 }
 
-// Collect gets all the data from the Pipes that are connected to this sink
-func (s *Sink) Collect() {
+// Collect gets all the data from the Pipes that are connected to this sink. The collected columns follow the given
+// order, e.g the order of the source CSV headers, and the ones that are not part of it follow in the order of the Pipes.
+// An explicit order set with SetColumns takes precedence over the given one
+func (s *Sink) Collect(order ...string) {
 	// there are many pipelines from which to get data from
 	// have to merge all maps into a single one
-	pipesData := []map[string][]float64{{}}
+	s.data = map[string]*column.Column{}
+	s.cols = nil
+	s.rowWise = map[string]bool{}
 	for _, p := range s.Pipes {
-		pipesData = append(pipesData, p.GetOutput())
+		if s.omitted[p] {
+			continue
+		}
+		out := p.GetTypedOutput()
+		for _, k := range sortedKeys(out) {
+			if !p.Aggregates() {
+				s.rowWise[k] = true
+			}
+			c, ok := s.data[k]
+			if !ok {
+				c = column.New(k, out[k].Type, nil)
+				s.data[k] = c
+				s.cols = append(s.cols, k)
+			}
+			// columns of the same name are merged, values keep their own type when formatted
+			c.Append(out[k])
+		}
+	}
+	for _, k := range sortedKeys(s.extra) {
+		if _, ok := s.data[k]; !ok {
+			s.data[k] = s.extra[k]
+			s.cols = append(s.cols, k)
+			s.rowWise[k] = true
+		}
+	}
+	failed := make([]string, 0, len(s.failed))
+	for k := range s.failed {
+		failed = append(failed, k)
+	}
+	sort.Strings(failed)
+	for _, k := range failed {
+		if _, ok := s.data[k]; !ok {
+			s.cols = append(s.cols, k)
+		}
 	}
-	s.data = Merge(pipesData...)
+	s.cols = orderColumns(order, s.cols)
 }
 
-// Dump tries to create the CSV file named filename with the results of the sink
-func (s *Sink) Dump() error {
-	cwd, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("failed to get the current working directory")
+// Omit leaves the output of the given pipes, e.g pipes that failed to flow, out of the columns the sink collects. It
+// replaces the pipes that were omitted before
+func (s *Sink) Omit(pipes ...*pipe.Pipe) {
+	s.omitted = map[*pipe.Pipe]bool{}
+	for _, p := range pipes {
+		s.omitted[p] = true
 	}
+}
 
-	f, err := os.Create(path.Join(cwd, s.filename))
+// Annotate adds the given columns, e.g the output columns of pipes that failed to flow, to the columns the sink collects
+// with the Failed value on every row, so that the dumped file tells which columns are missing. Collected columns of the
+// same name take precedence and the given columns replace the ones that were annotated before
+func (s *Sink) Annotate(cols ...string) {
+	s.failed = map[string]bool{}
+	for _, c := range cols {
+		s.failed[c] = true
+	}
+}
+
+// Include adds columns that did not flow through any pipe, e.g the unmapped columns a source passes through, to the
+// columns the sink collects. Pipe outputs take precedence over included columns of the same name
+func (s *Sink) Include(cols map[string]*column.Column) {
+	if s.extra == nil {
+		s.extra = map[string]*column.Column{}
+	}
+	for k, c := range cols {
+		s.extra[k] = c
+	}
+}
+
+// SetColumns sets an explicit order of the output columns, collected columns that are not part of it are written
+// after them in the order of the Pipes
+func (s *Sink) SetColumns(cols []string) {
+	s.order = cols
+}
+
+// Columns returns the explicit order of the output columns, nil when none was set
+func (s *Sink) Columns() []string {
+	return s.order
+}
+
+// Name returns the name of the sink, i.e the name of the CSV file it dumps into
+func (s *Sink) Name() string {
+	return s.filename
+}
+
+// Written returns the columns and the number of records, the header excluded, the sink last wrote to its file
+func (s *Sink) Written() ([]string, int) {
+	return s.written, s.rows
+}
+
+// Path returns the path of the CSV file the sink last wrote to, empty until the sink wrote to it
+func (s *Sink) Path() string {
+	return s.path
+}
+
+// BytesWritten returns the number of bytes the sink last wrote to its CSV file
+func (s *Sink) BytesWritten() int64 {
+	return s.bytes
+}
+
+// Dump tries to create the CSV file named filename with the results of the sink
+func (s *Sink) Dump() error {
+	f, err := s.create()
 	if err != nil {
-		return fmt.Errorf("failed to create the dump CSV file")
+		return err
 	}
 	defer func() {
 		if err := f.Close(); err != nil {
@@ -67,14 +192,187 @@ func (s *Sink) Dump() error {
 		}
 	}()
 
-	w := csv.NewWriter(f)
-	defer w.Flush()
+	w := csv.NewWriter(&countingWriter{w: f, n: &s.bytes})
+
+	s.written = s.columns()
+	s.rows = 0
 
-	for k, v := range s.data {
+	if s.Layout == TransposedLayout {
+		err = s.dumpTransposed(w)
+	} else {
+		err = s.dumpRows(w)
+	}
+	if err != nil {
+		return err
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("failed to write record to CSV file, err: %v", err)
+	}
+	return nil
+}
+
+// create creates the CSV file named filename in the current working directory
+func (s *Sink) create() (*os.File, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the current working directory")
+	}
+	p := path.Join(cwd, s.filename)
+	f, err := os.Create(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the dump CSV file")
+	}
+	s.path = p
+	s.bytes = 0
+	return f, nil
+}
+
+// columns returns the names of the collected columns, first in the explicit order then in the order they were collected
+func (s *Sink) columns() []string {
+	return orderColumns(s.order, s.cols)
+}
+
+// orderColumns orders the given column names, first in the given order then in the order of the names
+func orderColumns(order, names []string) []string {
+	cols := make([]string, 0, len(names))
+	available := map[string]bool{}
+	for _, c := range names {
+		available[c] = true
+	}
+	seen := map[string]bool{}
+	for _, c := range append(append([]string{}, order...), names...) {
+		if available[c] && !seen[c] {
+			cols = append(cols, c)
+			seen[c] = true
+		}
+	}
+	return cols
+}
+
+// dumpRows writes the header of columns followed by one record per row, padding shorter columns with Fill
+func (s *Sink) dumpRows(w *csv.Writer) error {
+	cols := s.columns()
+	if len(cols) == 0 {
+		return nil
+	}
+	if err := w.Write(cols); err != nil {
+		return fmt.Errorf("failed to write header to CSV file, err: %v", err)
+	}
+	return s.writeRecords(w, cols, s.data, s.rowWise)
+}
+
+// writeRecords writes one record per row of the given columns, padding shorter columns with Fill. When filters dropped
+// rows of any of the row wise columns, only the rows that are part of all the row wise columns are written so that the
+// values of a record always come from the same row, other columns, e.g aggregates, are written in order
+func (s *Sink) writeRecords(w *csv.Writer, cols []string, data map[string]*column.Column, rowWise map[string]bool) error {
+	aligned := alignRows(cols, data, rowWise)
+	rows := len(aligned)
+	for _, c := range cols {
+		if d, ok := data[c]; ok && (aligned == nil || !rowWise[c]) && d.Len() > rows {
+			rows = d.Len()
+		}
+	}
+	s.rows += rows
+	for i := 0; i < rows; i++ {
+		r := make([]string, len(cols))
+		for j, c := range cols {
+			d, ok := data[c]
+			if !ok && s.failed[c] {
+				r[j] = s.Failed
+				continue
+			}
+			k := i
+			if ok && aligned != nil && rowWise[c] {
+				k = -1
+				if i < len(aligned) {
+					k = aligned[i][c]
+				}
+			}
+			if ok && k >= 0 && k < d.Len() {
+				if d.IsNull(k) {
+					r[j] = s.Null
+				} else {
+					r[j] = formatValue(d.Values[k])
+				}
+			} else {
+				r[j] = s.Fill
+			}
+		}
+		if err := w.Write(r); err != nil {
+			return fmt.Errorf("failed to write record to CSV file, err: %v", err)
+		}
+	}
+	return nil
+}
+
+// alignRows returns, for every row that is part of all the given row wise columns, the index of its value in each of
+// them, in the order of the rows. It returns nil when no filter dropped rows of the row wise columns, values are then
+// written in order
+func alignRows(cols []string, data map[string]*column.Column, rowWise map[string]bool) []map[string]int {
+	var wise []string
+	filtered := false
+	for _, c := range cols {
+		if d, ok := data[c]; ok && rowWise[c] {
+			wise = append(wise, c)
+			filtered = filtered || d.Rows != nil
+		}
+	}
+	if !filtered {
+		return nil
+	}
+	index := map[string]map[int]int{}
+	for _, c := range wise {
+		d := data[c]
+		index[c] = make(map[int]int, d.Len())
+		for i := 0; i < d.Len(); i++ {
+			index[c][d.Row(i)] = i
+		}
+	}
+	var rows []int
+	for row := range index[wise[0]] {
+		rows = append(rows, row)
+	}
+	sort.Ints(rows)
+	aligned := make([]map[string]int, 0, len(rows))
+	for _, row := range rows {
+		rec := make(map[string]int, len(wise))
+		for _, c := range wise {
+			k, ok := index[c][row]
+			if !ok {
+				break
+			}
+			rec[c] = k
+		}
+		if len(rec) == len(wise) {
+			aligned = append(aligned, rec)
+		}
+	}
+	return aligned
+}
+
+// dumpTransposed writes every column as a row made of the column name followed by its values
+func (s *Sink) dumpTransposed(w *csv.Writer) error {
+	for _, k := range s.columns() {
+		if _, ok := s.data[k]; !ok {
+			// annotated columns of pipes that failed
+			if err := w.Write([]string{k, s.Failed}); err != nil {
+				return fmt.Errorf("failed to write record to CSV file, err: %v", err)
+			}
+			continue
+		}
+		v := s.data[k].Values
+		if len(v) > s.rows {
+			s.rows = len(v)
+		}
 		r := make([]string, 0, len(v)+1) // + 1 for the header
 		r = append(r, k)
-		for _, j := range v {
-			r = append(r, strconv.FormatFloat(j, 'f', Precision, FloatBitSize))
+		for i, j := range v {
+			if s.data[k].IsNull(i) {
+				r = append(r, s.Null)
+			} else {
+				r = append(r, formatValue(j))
+			}
 		}
 		if err := w.Write(r); err != nil {
 			return fmt.Errorf("failed to write record to CSV file, err: %v", err)
@@ -82,3 +380,8 @@ func (s *Sink) Dump() error {
 	}
 	return nil
 }
+
+// formatValue formats a value according to its type, floats are formatted with the sink Precision
+func formatValue(v interface{}) string {
+	return column.Format(v, Precision)
+}
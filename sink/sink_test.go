@@ -2,11 +2,15 @@ package sink
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/flaviuvadan/pipe-flow/column"
 	"github.com/flaviuvadan/pipe-flow/pipe"
 )
 
@@ -190,3 +194,310 @@ func TestSink_Dump(t *testing.T) {
 		})
 	}
 }
+
+func TestSink_Dump_Layout(t *testing.T) {
+	tests := []struct {
+		name     string
+		layout   Layout
+		fill     string
+		pipesOut []map[string][]float64
+		expected string
+	}{
+		{
+			name:   "test_dumps_rows_with_header",
+			layout: RowLayout,
+			pipesOut: []map[string][]float64{
+				{"a": {1, 2}},
+				{"b": {3, 4}},
+			},
+			expected: "a,b\n1.000,3.000\n2.000,4.000\n",
+		},
+		{
+			name:   "test_dumps_rows_padding_shorter_columns",
+			layout: RowLayout,
+			fill:   "NA",
+			pipesOut: []map[string][]float64{
+				{"a": {1, 2}},
+				{"b": {3}},
+			},
+			expected: "a,b\n1.000,3.000\n2.000,NA\n",
+		},
+		{
+			name:   "test_dumps_transposed_columns",
+			layout: TransposedLayout,
+			pipesOut: []map[string][]float64{
+				{"a": {1, 2}},
+				{"b": {3}},
+			},
+			expected: "a,1.000,2.000\nb,3.000\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipes := make([]*pipe.Pipe, len(tt.pipesOut))
+			for i, o := range tt.pipesOut {
+				pipes[i] = pipe.NewSingleOpsPipe("", nil)
+				pipes[i].SetOutput(o)
+			}
+			fn := tt.name + ".csv"
+			s, _ := NewSink(fn, pipes)
+			s.Layout = tt.layout
+			s.Fill = tt.fill
+			s.Collect()
+			assert.NoError(t, s.Dump())
+			content, err := ioutil.ReadFile(fn)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, string(content))
+			if err := os.Remove(fn); err != nil {
+				panic(fmt.Errorf("could not remove %v for tests teardown", fn))
+			}
+		})
+	}
+}
+
+func TestSink_Dump_WriteErr(t *testing.T) {
+	if _, err := os.Stat("/dev/full"); err != nil {
+		t.Skip("no /dev/full to fail writes on")
+	}
+	for _, layout := range []Layout{RowLayout, TransposedLayout} {
+		fn := "test_dump_write_err.csv"
+		if err := os.Symlink("/dev/full", fn); err != nil {
+			panic(fmt.Errorf("could not link %v for tests setup", fn))
+		}
+		p := pipe.NewSingleOpsPipe("", nil)
+		p.SetOutput(map[string][]float64{"a": {1, 2}})
+		s, _ := NewSink(fn, []*pipe.Pipe{p})
+		s.Layout = layout
+		s.Collect()
+		cwd, _ := os.Getwd()
+		assert.EqualError(t, s.Dump(), fmt.Sprintf("failed to write record to CSV file, err: write %s: no space left on device", path.Join(cwd, fn)))
+		if err := os.Remove(fn); err != nil {
+			panic(fmt.Errorf("could not remove %v for tests teardown", fn))
+		}
+	}
+}
+
+func TestSink_Dump_ColumnOrder(t *testing.T) {
+	tests := []struct {
+		name         string
+		order        []string
+		collectOrder []string
+		pipesOut     []map[string][]float64
+		expected     string
+	}{
+		{
+			name: "test_dumps_columns_in_pipe_order",
+			pipesOut: []map[string][]float64{
+				{"c": {1}},
+				{"a": {2}},
+				{"b": {3}},
+			},
+			expected: "c,a,b\n1.000,2.000,3.000\n",
+		},
+		{
+			name:  "test_dumps_columns_in_explicit_order",
+			order: []string{"b", "c", "a"},
+			pipesOut: []map[string][]float64{
+				{"c": {1}},
+				{"a": {2}},
+				{"b": {3}},
+			},
+			expected: "b,c,a\n3.000,1.000,2.000\n",
+		},
+		{
+			name:  "test_dumps_unordered_columns_after_explicit_order",
+			order: []string{"b", "missing"},
+			pipesOut: []map[string][]float64{
+				{"c": {1}},
+				{"a": {2}},
+				{"b": {3}},
+			},
+			expected: "b,c,a\n3.000,1.000,2.000\n",
+		},
+		{
+			name:         "test_dumps_columns_in_collect_order",
+			collectOrder: []string{"a", "b"},
+			pipesOut: []map[string][]float64{
+				{"c": {1}},
+				{"a": {2}},
+				{"b": {3}},
+			},
+			expected: "a,b,c\n2.000,3.000,1.000\n",
+		},
+		{
+			name:         "test_explicit_order_takes_precedence_over_collect_order",
+			order:        []string{"c"},
+			collectOrder: []string{"a", "b"},
+			pipesOut: []map[string][]float64{
+				{"c": {1}},
+				{"a": {2}},
+				{"b": {3}},
+			},
+			expected: "c,a,b\n1.000,2.000,3.000\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipes := make([]*pipe.Pipe, len(tt.pipesOut))
+			for i, o := range tt.pipesOut {
+				pipes[i] = pipe.NewSingleOpsPipe("", nil)
+				pipes[i].SetOutput(o)
+			}
+			fn := tt.name + ".csv"
+			s, _ := NewSink(fn, pipes)
+			s.SetColumns(tt.order)
+			s.Collect(tt.collectOrder...)
+			assert.Equal(t, tt.order, s.Columns())
+			assert.NoError(t, s.Dump())
+			content, err := ioutil.ReadFile(fn)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, string(content))
+			if err := os.Remove(fn); err != nil {
+				panic(fmt.Errorf("could not remove %v for tests teardown", fn))
+			}
+		})
+	}
+}
+
+func TestSink_Dump_Types(t *testing.T) {
+	p := pipe.NewSingleOpsPipe("", nil)
+	p.SetTypedOutput(map[string]*column.Column{
+		"id":     column.New("id", column.Int, []interface{}{int64(1), int64(2)}),
+		"name":   column.New("name", column.String, []interface{}{"ann", "bob"}),
+		"active": column.New("active", column.Bool, []interface{}{true, false}),
+		"joined": column.New("joined", column.Time, []interface{}{
+			time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+			time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		}),
+		"score": column.FromFloats("score", []float64{1.5, 2}),
+	})
+	fn := "test_dump_types.csv"
+	s, _ := NewSink(fn, []*pipe.Pipe{p})
+	s.SetColumns([]string{"id", "name", "active", "joined", "score"})
+	s.Collect()
+	assert.NoError(t, s.Dump())
+	content, err := ioutil.ReadFile(fn)
+	assert.NoError(t, err)
+	assert.Equal(t, "id,name,active,joined,score\n1,ann,true,2020-01-02,1.500\n2,bob,false,2020-01-02T03:04:05Z,2.000\n", string(content))
+	if err := os.Remove(fn); err != nil {
+		panic(fmt.Errorf("could not remove %v for tests teardown", fn))
+	}
+}
+
+func TestSink_Dump_Nulls(t *testing.T) {
+	c := column.New("a", column.Float, nil)
+	c.Push(1.0)
+	c.Push(nil)
+	p := pipe.NewSingleOpsPipe("", nil)
+	p.SetTypedOutput(map[string]*column.Column{"a": c})
+	fn := "test_dump_nulls.csv"
+	s, _ := NewSink(fn, []*pipe.Pipe{p})
+	s.Null = "NA"
+	s.Collect()
+	assert.NoError(t, s.Dump())
+	content, err := ioutil.ReadFile(fn)
+	assert.NoError(t, err)
+	assert.Equal(t, "a\n1.000\nNA\n", string(content))
+	if err := os.Remove(fn); err != nil {
+		panic(fmt.Errorf("could not remove %v for tests teardown", fn))
+	}
+}
+
+func TestSink_Include(t *testing.T) {
+	p := pipe.NewSingleOpsPipe("", nil)
+	p.SetOutput(map[string][]float64{"a": {1, 2}})
+	fn := "test_dump_included.csv"
+	s, _ := NewSink(fn, []*pipe.Pipe{p})
+	s.SetColumns([]string{"b", "a"})
+	s.Include(map[string]*column.Column{
+		"a": column.FromFloats("a", []float64{9, 9}),
+		"b": column.New("b", column.String, []interface{}{"x", "y"}),
+	})
+	s.Collect()
+	assert.NoError(t, s.Dump())
+	cols, rows := s.Written()
+	assert.Equal(t, []string{"b", "a"}, cols)
+	assert.Equal(t, 2, rows)
+	content, err := ioutil.ReadFile(fn)
+	assert.NoError(t, err)
+	assert.Equal(t, "b,a\nx,1.000\ny,2.000\n", string(content))
+	cwd, _ := os.Getwd()
+	assert.Equal(t, path.Join(cwd, fn), s.Path())
+	assert.Equal(t, int64(len(content)), s.BytesWritten())
+	if err := os.Remove(fn); err != nil {
+		panic(fmt.Errorf("could not remove %v for tests teardown", fn))
+	}
+}
+
+func TestSink_Dump_DroppedRows(t *testing.T) {
+	filter := pipe.NewFilterPipe("filter", func(v float64) (bool, error) { return v > 1, nil })
+	filter.SetInput(map[string][]float64{"a": {1, 2, 3}})
+	assert.NoError(t, filter.Flow())
+	sum := pipe.NewAggregateOpPipe("sum", func(values []float64) (float64, error) {
+		return values[0] + values[1] + values[2], nil
+	})
+	sum.SetInput(map[string][]float64{"total": {1, 2, 3}})
+	assert.NoError(t, sum.Flow())
+	fn := "test_dump_dropped_rows.csv"
+	s, _ := NewSink(fn, []*pipe.Pipe{filter, sum})
+	s.Include(map[string]*column.Column{
+		"b": column.New("b", column.String, []interface{}{"x", "y", "z"}),
+	})
+	s.Collect()
+	assert.NoError(t, s.Dump())
+	_, rows := s.Written()
+	assert.Equal(t, 2, rows)
+	content, err := ioutil.ReadFile(fn)
+	assert.NoError(t, err)
+	assert.Equal(t, "a,total,b\n2.000,6.000,y\n3.000,,z\n", string(content))
+	if err := os.Remove(fn); err != nil {
+		panic(fmt.Errorf("could not remove %v for tests teardown", fn))
+	}
+}
+
+func TestSink_OmitAndAnnotate(t *testing.T) {
+	tests := []struct {
+		name     string
+		layout   Layout
+		annotate []string
+		expected string
+	}{
+		{
+			name:     "test_omits_failed_pipes",
+			expected: "a\n1.000\n2.000\n",
+		},
+		{
+			name:     "test_annotates_failed_columns",
+			annotate: []string{"b"},
+			expected: "a,b\n1.000,#FAILED\n2.000,#FAILED\n",
+		},
+		{
+			name:     "test_annotates_failed_columns_transposed",
+			layout:   TransposedLayout,
+			annotate: []string{"b"},
+			expected: "a,1.000,2.000\nb,#FAILED\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := pipe.NewSingleOpsPipe("a", nil)
+			a.SetOutput(map[string][]float64{"a": {1, 2}})
+			b := pipe.NewSingleOpsPipe("b", nil)
+			b.SetOutput(map[string][]float64{"b": {9}})
+			fn := fmt.Sprintf("%s.csv", tt.name)
+			s, _ := NewSink(fn, []*pipe.Pipe{a, b})
+			s.Layout = tt.layout
+			s.SetColumns([]string{"a", "b"})
+			s.Omit(b)
+			s.Annotate(tt.annotate...)
+			s.Collect()
+			assert.NoError(t, s.Dump())
+			content, err := ioutil.ReadFile(fn)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, string(content))
+			if err := os.Remove(fn); err != nil {
+				panic(fmt.Errorf("could not remove %v for tests teardown", fn))
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package sink
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/flaviuvadan/pipe-flow/column"
+)
+
+// Begin creates the CSV file of the sink and writes the header of the given columns so that rows of a streaming source
+// can be written as they arrive, see Write and End. The columns follow the explicit order set with SetColumns, then the
+// given order, e.g the order of the source CSV header, and then their own order. Only RowLayout can be streamed
+func (s *Sink) Begin(cols []string, order ...string) error {
+	if s.Layout != RowLayout {
+		return fmt.Errorf("only the row layout can be streamed")
+	}
+	f, err := s.create()
+	if err != nil {
+		return err
+	}
+	s.file = f
+	s.writer = csv.NewWriter(&countingWriter{w: f, n: &s.bytes})
+	s.header = orderColumns(s.order, orderColumns(order, cols))
+	// only the columns of pipes that failed to flow from a whole source are annotated
+	s.failed = nil
+	s.written = s.header
+	s.rows = 0
+	if err := s.writer.Write(s.header); err != nil {
+		return fmt.Errorf("failed to write header to CSV file, err: %v", err)
+	}
+	return nil
+}
+
+// Write writes one record per row of the given chunk, columns of the header that are not part of the chunk, such as
+// the ones of accumulators, are padded with Fill. Rows that filters dropped from any column of the chunk are left out
+func (s *Sink) Write(chunk map[string]*column.Column) error {
+	if s.writer == nil {
+		return fmt.Errorf("cannot write to a sink that did not begin")
+	}
+	rowWise := make(map[string]bool, len(chunk))
+	for k := range chunk {
+		rowWise[k] = true
+	}
+	return s.writeRecords(s.writer, s.header, chunk, rowWise)
+}
+
+// End writes the given final values, e.g the results of accumulators, as a last record and closes the CSV file
+func (s *Sink) End(final map[string]*column.Column) error {
+	if s.writer == nil {
+		return fmt.Errorf("cannot end a sink that did not begin")
+	}
+	if err := s.writeRecords(s.writer, s.header, final, nil); err != nil {
+		return err
+	}
+	s.writer.Flush()
+	if err := s.writer.Error(); err != nil {
+		return fmt.Errorf("failed to write record to CSV file, err: %v", err)
+	}
+	return s.close()
+}
+
+// Abort closes and removes the CSV file of a sink that began so no partial results are left behind
+func (s *Sink) Abort() error {
+	if s.file == nil {
+		return nil
+	}
+	name := s.file.Name()
+	if err := s.close(); err != nil {
+		return err
+	}
+	if err := os.Remove(name); err != nil {
+		return fmt.Errorf("failed to remove partial CSV file %s, err: %v", path.Base(name), err)
+	}
+	return nil
+}
+
+// close closes the CSV file the sink streams to
+func (s *Sink) close() error {
+	err := s.file.Close()
+	s.file = nil
+	s.writer = nil
+	if err != nil {
+		return fmt.Errorf("failed to dump results after processing, err: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package sink
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flaviuvadan/pipe-flow/column"
+	"github.com/flaviuvadan/pipe-flow/pipe"
+)
+
+func TestSink_Write(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   []string
+		chunks   []map[string][]float64
+		final    map[string][]float64
+		expected string
+	}{
+		{
+			name:   "test_writes_rows_as_they_arrive",
+			header: []string{"a", "b"},
+			chunks: []map[string][]float64{
+				{"a": {1}, "b": {2}},
+				{"a": {3}, "b": {4}},
+			},
+			expected: "a,b\n1.000,2.000\n3.000,4.000\n",
+		},
+		{
+			name:   "test_writes_final_values_last",
+			header: []string{"a", "b"},
+			chunks: []map[string][]float64{
+				{"a": {1}},
+				{"a": {3}},
+			},
+			final:    map[string][]float64{"b": {10}},
+			expected: "a,b\n1.000,\n3.000,\n,10.000\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn := tt.name + ".csv"
+			s, _ := NewSink(fn, []*pipe.Pipe{pipe.NewSingleOpsPipe("", nil)})
+			assert.NoError(t, s.Begin(tt.header))
+			for _, c := range tt.chunks {
+				assert.NoError(t, s.Write(columns(c)))
+			}
+			assert.NoError(t, s.End(columns(tt.final)))
+			cols, rows := s.Written()
+			assert.Equal(t, tt.header, cols)
+			assert.Equal(t, len(tt.chunks)+len(tt.final), rows)
+			content, err := ioutil.ReadFile(fn)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, string(content))
+			if err := os.Remove(fn); err != nil {
+				panic(fmt.Errorf("could not remove %v for tests teardown", fn))
+			}
+		})
+	}
+}
+
+func TestSink_Abort(t *testing.T) {
+	fn := "test_abort.csv"
+	s, _ := NewSink(fn, []*pipe.Pipe{pipe.NewSingleOpsPipe("", nil)})
+	assert.NoError(t, s.Begin([]string{"a"}))
+	assert.NoError(t, s.Write(columns(map[string][]float64{"a": {1}})))
+	assert.NoError(t, s.Abort())
+	_, err := os.Stat(fn)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSink_Begin(t *testing.T) {
+	s, _ := NewSink("test_begin.csv", []*pipe.Pipe{pipe.NewSingleOpsPipe("", nil)})
+	s.Layout = TransposedLayout
+	assert.EqualError(t, s.Begin([]string{"a"}), "only the row layout can be streamed")
+	assert.EqualError(t, s.Write(nil), "cannot write to a sink that did not begin")
+}
+
+// columns converts float64 values into Float columns
+func columns(m map[string][]float64) map[string]*column.Column {
+	cols := map[string]*column.Column{}
+	for k, v := range m {
+		cols[k] = column.FromFloats(k, v)
+	}
+	return cols
+}
@@ -1,5 +1,12 @@
 package sink
 
+import (
+	"io"
+	"sort"
+
+	"github.com/flaviuvadan/pipe-flow/column"
+)
+
 // Merge create a single map by combining all the given maps
 func Merge(ms ...map[string][]float64) map[string][]float64 {
 	if ms == nil || len(ms) == 0 {
@@ -16,3 +23,26 @@ func Merge(ms ...map[string][]float64) map[string][]float64 {
 	}
 	return res
 }
+
+// sortedKeys returns the keys of the given map in alphabetical order
+func sortedKeys(m map[string]*column.Column) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// countingWriter counts the bytes written to the writer it wraps
+type countingWriter struct {
+	w io.Writer // the wrapped writer
+	n *int64    // number of bytes written so far
+}
+
+// Write writes to the wrapped writer and counts the bytes that were written
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	*c.n += int64(n)
+	return n, err
+}
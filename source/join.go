@@ -0,0 +1,246 @@
+package source
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/flaviuvadan/pipe-flow/column"
+	"github.com/flaviuvadan/pipe-flow/pipe"
+)
+
+// JoinKind tells which rows a join keeps
+type JoinKind int
+
+const (
+	InnerJoin JoinKind = iota // keeps the rows whose key is part of both sources
+	LeftJoin                  // keeps all the rows of the left source, right columns are null when the key does not match
+	FullJoin                  // keeps all the rows of both sources, columns of the side that does not match are null
+)
+
+// String returns the name of the join kind
+func (k JoinKind) String() string {
+	switch k {
+	case InnerJoin:
+		return "inner"
+	case LeftJoin:
+		return "left"
+	case FullJoin:
+		return "full outer"
+	}
+	return fmt.Sprintf("JoinKind(%d)", int(k))
+}
+
+// Join describes how two sources are joined into one, see NewJoinedSource
+type Join struct {
+	Kind     JoinKind // which rows the join keeps
+	On       []string // key columns, part of both sources
+	OneToOne bool     // whether every key must be unique in both sources, duplicate keys fail the join
+}
+
+// keySeparator separates the values of the key columns of a row, it is not expected to be part of any value
+const keySeparator = "\x00"
+
+// NewJoinedSource returns a new instance of a Source whose rows are the rows of the left and right sources joined on
+// the key columns. The joined source has the columns of the left source followed by the columns of the right source
+// that are not keys, and it is used like any other source: pipes, row pipes and options bind to its columns. Null keys
+// never match. The left and right sources are read whole and should not have pipes of their own
+func NewJoinedSource(dsc string, left, right *Source, j Join, pps map[string]*pipe.Pipe, opts ...Option) (*Source, error) {
+	if left.Streaming() || right.Streaming() {
+		return nil, fmt.Errorf("cannot join streaming sources")
+	}
+	if len(j.On) == 0 {
+		return nil, fmt.Errorf("cannot join sources (%s) and (%s) without key columns", left.Description, right.Description)
+	}
+	lkeys, rkeys, err := joinKeys(left, right, j.On)
+	if err != nil {
+		return nil, err
+	}
+	isKey := map[string]bool{}
+	for _, k := range j.On {
+		isKey[k] = true
+	}
+	columns := append([]string{}, left.columns...)
+	var rightCols []string
+	for _, c := range right.columns {
+		if isKey[c] {
+			continue
+		}
+		if _, ok := left.data[c]; ok {
+			return nil, fmt.Errorf("column (%s) is part of both sources (%s) and (%s) without being a key", c, left.Description, right.Description)
+		}
+		rightCols = append(rightCols, c)
+	}
+	columns = append(columns, rightCols...)
+
+	pairs, err := joinRows(left, right, lkeys, rkeys, j)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Source{
+		Description: dsc,
+		filename:    fmt.Sprintf("%s joined with %s", left.filename, right.filename),
+		Pipes:       pps,
+		columns:     columns,
+		types:       map[string]column.Type{},
+		data:        map[string]*column.Column{},
+		rows:        len(pairs),
+		bytesRead:   left.bytesRead + right.bytesRead,
+		fanOut:      map[string][]*pipe.Pipe{},
+
+		nullPolicies: map[string]NullPolicy{},
+		nullCounts:   map[string]int{},
+		last:         map[string]interface{}{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.Streaming() {
+		return nil, fmt.Errorf("joined source (%s) cannot stream", dsc)
+	}
+	for _, c := range left.columns {
+		if !isKey[c] {
+			s.data[c] = joinColumn(c, left.data[c].Type, func(p [2]int) (*column.Column, int) {
+				return left.data[c], p[0]
+			}, pairs)
+			continue
+		}
+		s.data[c] = joinColumn(c, lkeys[c].Type, func(p [2]int) (*column.Column, int) {
+			if p[0] < 0 {
+				// rows that are only part of the right source take their key from it
+				return rkeys[c], p[1]
+			}
+			return lkeys[c], p[0]
+		}, pairs)
+	}
+	for _, c := range rightCols {
+		s.data[c] = joinColumn(c, right.data[c].Type, func(p [2]int) (*column.Column, int) {
+			return right.data[c], p[1]
+		}, pairs)
+	}
+	for _, c := range columns {
+		s.types[c] = s.data[c].Type
+		s.nullCounts[c] = s.data[c].NullCount()
+	}
+	if err := s.setPipeData(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// joinKeys returns the key columns of the left and right sources, Int keys are compared as Float when the other side
+// is Float, other types have to match
+func joinKeys(left, right *Source, on []string) (map[string]*column.Column, map[string]*column.Column, error) {
+	lkeys := map[string]*column.Column{}
+	rkeys := map[string]*column.Column{}
+	for _, k := range on {
+		l, ok := left.data[k]
+		if !ok {
+			return nil, nil, fmt.Errorf("key column (%s) is not part of source (%s)", k, left.Description)
+		}
+		r, ok := right.data[k]
+		if !ok {
+			return nil, nil, fmt.Errorf("key column (%s) is not part of source (%s)", k, right.Description)
+		}
+		if l.Type == column.Int && r.Type == column.Float {
+			l, _ = l.As(column.Float)
+		}
+		if l.Type == column.Float && r.Type == column.Int {
+			r, _ = r.As(column.Float)
+		}
+		if l.Type != r.Type {
+			return nil, nil, fmt.Errorf("key column (%s) is of type %v in source (%s) and of type %v in source (%s)", k, l.Type, left.Description, r.Type, right.Description)
+		}
+		lkeys[k] = l
+		rkeys[k] = r
+	}
+	return lkeys, rkeys, nil
+}
+
+// joinRows returns the pairs of left and right row indices the join keeps, in the order of the left rows followed by
+// the right rows that did not match. An index of -1 means the row is not part of that side
+func joinRows(left, right *Source, lkeys, rkeys map[string]*column.Column, j Join) ([][2]int, error) {
+	index := map[string][]int{}
+	for i := 0; i < right.length(); i++ {
+		k, ok := rowKey(rkeys, j.On, i)
+		if !ok {
+			continue
+		}
+		if j.OneToOne && len(index[k]) > 0 {
+			return nil, fmt.Errorf("duplicate key (%s) in source (%s), a one-to-one join requires unique keys", printKey(k), right.Description)
+		}
+		index[k] = append(index[k], i)
+	}
+	var pairs [][2]int
+	matched := make([]bool, right.length())
+	seen := map[string]bool{}
+	for i := 0; i < left.length(); i++ {
+		k, ok := rowKey(lkeys, j.On, i)
+		if ok && j.OneToOne {
+			if seen[k] {
+				return nil, fmt.Errorf("duplicate key (%s) in source (%s), a one-to-one join requires unique keys", printKey(k), left.Description)
+			}
+			seen[k] = true
+		}
+		var rs []int
+		if ok {
+			rs = index[k]
+		}
+		for _, r := range rs {
+			pairs = append(pairs, [2]int{i, r})
+			matched[r] = true
+		}
+		if len(rs) == 0 && j.Kind != InnerJoin {
+			pairs = append(pairs, [2]int{i, -1})
+		}
+	}
+	if j.Kind == FullJoin {
+		for r, m := range matched {
+			if !m {
+				pairs = append(pairs, [2]int{-1, r})
+			}
+		}
+	}
+	return pairs, nil
+}
+
+// rowKey returns the key of the given row, false when any of its key values is null
+func rowKey(keys map[string]*column.Column, on []string, row int) (string, bool) {
+	parts := make([]string, len(on))
+	for i, k := range on {
+		c := keys[k]
+		if c.IsNull(row) {
+			return "", false
+		}
+		parts[i] = column.Format(c.Values[row], -1)
+	}
+	return strings.Join(parts, keySeparator), true
+}
+
+// printKey formats a row key for error messages
+func printKey(k string) string {
+	return strings.ReplaceAll(k, keySeparator, ", ")
+}
+
+// joinColumn builds the named column of a joined source, from returns the column and row every pair takes its value
+// from, a negative row means the value is null
+func joinColumn(name string, t column.Type, from func(p [2]int) (*column.Column, int), pairs [][2]int) *column.Column {
+	col := column.New(name, t, make([]interface{}, 0, len(pairs)))
+	for _, p := range pairs {
+		c, row := from(p)
+		if row < 0 || c.IsNull(row) {
+			col.Push(nil)
+			continue
+		}
+		col.Push(c.Values[row])
+	}
+	return col
+}
+
+// length returns the number of rows of the data of the source, rows dropped because of nulls excluded
+func (s *Source) length() int {
+	for _, c := range s.data {
+		return c.Len()
+	}
+	return 0
+}
@@ -0,0 +1,123 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flaviuvadan/pipe-flow/column"
+	"github.com/flaviuvadan/pipe-flow/pipe"
+)
+
+// nullable returns a column of the given values where nil values are null
+func nullable(name string, t column.Type, values ...interface{}) *column.Column {
+	c := column.New(name, t, nil)
+	for _, v := range values {
+		c.Push(v)
+	}
+	return c
+}
+
+func TestNewJoinedSource(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		right       string
+		join        Join
+		expected    map[string]*column.Column
+		expectedErr error
+	}{
+		{
+			name:  "test_inner_join_keeps_matching_rows",
+			right: "test_join_right.csv",
+			join:  Join{Kind: InnerJoin, On: []string{"id"}, OneToOne: true},
+			expected: map[string]*column.Column{
+				"id":    nullable("id", column.Int, int64(2), int64(3)),
+				"name":  nullable("name", column.String, "bob", "cid"),
+				"score": nullable("score", column.Float, 20.5, 30.0),
+			},
+		},
+		{
+			name:  "test_left_join_keeps_left_rows",
+			right: "test_join_right.csv",
+			join:  Join{Kind: LeftJoin, On: []string{"id"}},
+			expected: map[string]*column.Column{
+				"id":    nullable("id", column.Int, int64(1), int64(2), int64(3)),
+				"name":  nullable("name", column.String, "ann", "bob", "cid"),
+				"score": nullable("score", column.Float, nil, 20.5, 30.0),
+			},
+		},
+		{
+			name:  "test_full_join_keeps_all_rows",
+			right: "test_join_right.csv",
+			join:  Join{Kind: FullJoin, On: []string{"id"}},
+			expected: map[string]*column.Column{
+				"id":    nullable("id", column.Int, int64(1), int64(2), int64(3), int64(4)),
+				"name":  nullable("name", column.String, "ann", "bob", "cid", nil),
+				"score": nullable("score", column.Float, nil, 20.5, 30.0, 40.0),
+			},
+		},
+		{
+			name:  "test_join_repeats_rows_of_duplicate_keys",
+			right: "test_join_dup.csv",
+			join:  Join{Kind: InnerJoin, On: []string{"id"}},
+			expected: map[string]*column.Column{
+				"id":    nullable("id", column.Int, int64(2), int64(2)),
+				"name":  nullable("name", column.String, "bob", "bob"),
+				"score": nullable("score", column.Int, int64(20), int64(21)),
+			},
+		},
+		{
+			name:        "test_one_to_one_join_returns_err_on_duplicate_keys",
+			right:       "test_join_dup.csv",
+			join:        Join{Kind: InnerJoin, On: []string{"id"}, OneToOne: true},
+			expectedErr: fmt.Errorf("duplicate key (2) in source (right), a one-to-one join requires unique keys"),
+		},
+		{
+			name:        "test_returns_err_on_missing_key_column",
+			right:       "test_join_right.csv",
+			join:        Join{Kind: InnerJoin, On: []string{"name"}},
+			expectedErr: fmt.Errorf("key column (name) is not part of source (right)"),
+		},
+		{
+			name:        "test_returns_err_on_shared_column_that_is_not_a_key",
+			right:       "test_join_left.csv",
+			join:        Join{Kind: InnerJoin, On: []string{"id"}},
+			expectedErr: fmt.Errorf("column (name) is part of both sources (left) and (right) without being a key"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			left, err := NewSource("left", "test_join_left.csv", nil)
+			assert.NoError(t, err)
+			right, err := NewSource("right", tt.right, nil)
+			assert.NoError(t, err)
+			s, err := NewJoinedSource("joined", left, right, tt.join, nil)
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"id", "name", "score"}, s.Columns())
+			assert.Equal(t, tt.expected, s.data)
+		})
+	}
+}
+
+func TestNewJoinedSource_Pipes(t *testing.T) {
+	left, _ := NewSource("left", "test_join_left.csv", nil)
+	right, _ := NewSource("right", "test_join_right.csv", nil)
+	label := pipe.NewRowPipe("label", []string{"name", "score"}, map[string]column.Type{"label": column.String},
+		func(_ context.Context, r pipe.Record) (pipe.Record, error) {
+			return pipe.Record{"label": fmt.Sprintf("%v:%v", r["name"], r["score"])}, nil
+		})
+	_, err := NewJoinedSource("joined", left, right, Join{Kind: InnerJoin, On: []string{"id"}}, nil, WithRowPipes(label))
+	assert.NoError(t, err)
+	assert.NoError(t, label.Flow())
+	assert.Equal(t, []interface{}{"bob:20.5", "cid:30"}, label.GetTypedOutput()["label"].Values)
+
+	_, err = NewJoinedSource("joined", left, right, Join{Kind: InnerJoin, On: []string{"id"}}, nil, WithChunkSize(1))
+	assert.EqualError(t, err, "joined source (joined) cannot stream")
+}
@@ -0,0 +1,122 @@
+package source
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/flaviuvadan/pipe-flow/column"
+)
+
+// DefaultNullTokens are the CSV cells a source treats as null when no tokens are specified. They do not apply to String
+// columns, whose empty cells and "NA" cells are valid strings, unless they are given to WithNullTokens
+var DefaultNullTokens = []string{"", "NA"}
+
+// NullAction tells what a source does with the null cells of a column
+type NullAction int
+
+const (
+	NullFail        NullAction = iota // fail the whole source, this is the default
+	NullDrop                          // drop the whole row the null cell is part of
+	NullFill                          // replace the null cell with the Fill value of the policy
+	NullNaN                           // replace the null cell with NaN, only valid for float64 columns
+	NullForwardFill                   // replace the null cell with the last valid value of the column, if any
+	NullMark                          // keep the cell as null in the validity bitmap of the column
+)
+
+// NullPolicy tells a source how to handle the null cells of a column
+type NullPolicy struct {
+	Action NullAction // what to do with null cells
+	Fill   string     // raw value that replaces null cells when Action is NullFill, parsed with the type of the column
+}
+
+// WithNullPolicy sets the null policy of the given CSV column
+func WithNullPolicy(col string, p NullPolicy) Option {
+	return func(s *Source) {
+		s.nullPolicies[col] = p
+	}
+}
+
+// WithDefaultNullPolicy sets the null policy of all the CSV columns that do not have their own
+func WithDefaultNullPolicy(p NullPolicy) Option {
+	return func(s *Source) {
+		s.defaultNullPolicy = p
+	}
+}
+
+// WithNullTokens sets the CSV cells the source treats as null in columns of any type, DefaultNullTokens otherwise
+func WithNullTokens(tokens ...string) Option {
+	return func(s *Source) {
+		s.defaultTokens = false
+		s.nullTokens = map[string]bool{}
+		for _, t := range tokens {
+			s.nullTokens[t] = true
+		}
+	}
+}
+
+// NullCounts returns the number of null cells the source read in every CSV column
+func (s *Source) NullCounts() map[string]int {
+	return s.nullCounts
+}
+
+// nullPolicy returns the null policy of the given CSV column
+func (s *Source) nullPolicy(col string) NullPolicy {
+	if p, ok := s.nullPolicies[col]; ok {
+		return p
+	}
+	return s.defaultNullPolicy
+}
+
+// isNull tells whether the given CSV cell is null, text tells whether the cell is part of a String column, which the
+// DefaultNullTokens do not apply to
+func (s *Source) isNull(raw string, text bool) bool {
+	if text && s.defaultTokens {
+		return false
+	}
+	return s.nullTokens[raw]
+}
+
+// dropNullRows removes the rows that have a null cell in a column whose policy is NullDrop, the indices of the rows
+// that are kept are returned along with them. texts tells which columns hold strings, see textColumns
+func (s *Source) dropNullRows(rows [][]string, texts map[string]bool) ([][]string, []int) {
+	kept := make([][]string, 0, len(rows))
+	indices := make([]int, 0, len(rows))
+	for j, r := range rows {
+		drop := false
+		for i, c := range s.columns {
+			if s.isNull(r[i], texts[c]) && s.nullPolicy(c).Action == NullDrop {
+				drop = true
+				break
+			}
+		}
+		if !drop {
+			kept = append(kept, r)
+			indices = append(indices, j)
+		}
+	}
+	return kept, indices
+}
+
+// fillNull returns the value that replaces a null cell of the given column on the given row according to the null
+// policy of the column, nil when the cell stays null
+func (s *Source) fillNull(col string, t column.Type, row int) (interface{}, error) {
+	p := s.nullPolicy(col)
+	switch p.Action {
+	case NullFill:
+		v, err := t.Parse(p.Fill)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fill null value on row %d of col (%s), err: %v", row, col, err)
+		}
+		return v, nil
+	case NullNaN:
+		if t != column.Float {
+			return nil, fmt.Errorf("cannot fill col (%s) of type %v with NaN", col, t)
+		}
+		return math.NaN(), nil
+	case NullForwardFill:
+		return s.last[col], nil
+	case NullMark, NullDrop:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("null value on row %d of col (%s)", row, col)
+}
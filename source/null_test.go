@@ -0,0 +1,161 @@
+package source
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flaviuvadan/pipe-flow/column"
+)
+
+func TestNewSource_NullPolicy(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name           string
+		opts           []Option
+		expected       map[string][]interface{}
+		expectedCounts map[string]int
+		expectedErr    error
+	}{
+		{
+			name:        "test_fails_on_null_by_default",
+			opts:        nil,
+			expectedErr: fmt.Errorf("null value on row 1 of col (a)"),
+		},
+		{
+			name: "test_drops_rows_with_nulls",
+			opts: []Option{WithDefaultNullPolicy(NullPolicy{Action: NullDrop})},
+			expected: map[string][]interface{}{
+				"a": {int64(4)},
+				"b": {int64(4)},
+			},
+			expectedCounts: map[string]int{"a": 1, "b": 2},
+		},
+		{
+			name: "test_fills_nulls_with_constant",
+			opts: []Option{WithDefaultNullPolicy(NullPolicy{Action: NullFill, Fill: "0"})},
+			expected: map[string][]interface{}{
+				"a": {int64(1), int64(0), int64(3), int64(4)},
+				"b": {0.0, 2.5, 0.0, 4.0},
+			},
+			expectedCounts: map[string]int{"a": 1, "b": 2},
+		},
+		{
+			name: "test_fills_nulls_with_nan",
+			opts: []Option{
+				WithNullPolicy("a", NullPolicy{Action: NullNaN}),
+				WithNullPolicy("b", NullPolicy{Action: NullNaN}),
+			},
+			expected: map[string][]interface{}{
+				"a": {1.0, math.NaN(), 3.0, 4.0},
+				"b": {math.NaN(), 2.5, math.NaN(), 4.0},
+			},
+			expectedCounts: map[string]int{"a": 1, "b": 2},
+		},
+		{
+			name: "test_forward_fills_nulls",
+			opts: []Option{WithDefaultNullPolicy(NullPolicy{Action: NullForwardFill})},
+			expected: map[string][]interface{}{
+				"a": {int64(1), int64(1), int64(3), int64(4)},
+				"b": {nil, 2.5, 2.5, 4.0},
+			},
+			expectedCounts: map[string]int{"a": 1, "b": 2},
+		},
+		{
+			name: "test_marks_nulls",
+			opts: []Option{WithDefaultNullPolicy(NullPolicy{Action: NullMark})},
+			expected: map[string][]interface{}{
+				"a": {int64(1), nil, int64(3), int64(4)},
+				"b": {nil, 2.5, nil, 4.0},
+			},
+			expectedCounts: map[string]int{"a": 1, "b": 2},
+		},
+		{
+			name: "test_uses_custom_null_tokens",
+			opts: []Option{
+				WithNullTokens(""),
+				WithDefaultNullPolicy(NullPolicy{Action: NullMark}),
+				WithTypes(map[string]column.Type{"b": column.String}),
+			},
+			expected: map[string][]interface{}{
+				"a": {int64(1), nil, int64(3), int64(4)},
+				"b": {"NA", "2.5", nil, "4"},
+			},
+			expectedCounts: map[string]int{"a": 1, "b": 1},
+		},
+		{
+			name:        "test_returns_err_on_nan_fill_of_non_float_column",
+			opts:        []Option{WithTypes(map[string]column.Type{"a": column.Int}), WithNullPolicy("a", NullPolicy{Action: NullNaN})},
+			expectedErr: fmt.Errorf("cannot fill col (a) of type int64 with NaN"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSource("test", "test_7.csv", nil, tt.opts...)
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedCounts, s.NullCounts())
+			for c, expected := range tt.expected {
+				col := s.data[c]
+				assert.Equal(t, len(expected), col.Len())
+				for i, v := range expected {
+					if v == nil {
+						assert.True(t, col.IsNull(i), "value %d of col %s should be null", i, c)
+						continue
+					}
+					assert.False(t, col.IsNull(i))
+					if f, ok := v.(float64); ok && math.IsNaN(f) {
+						assert.True(t, math.IsNaN(col.Values[i].(float64)))
+						continue
+					}
+					assert.Equal(t, v, col.Values[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNewSource_NullTokens_StringColumns(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name           string
+		opts           []Option
+		expected       []interface{}
+		expectedCounts map[string]int
+	}{
+		{
+			name:           "test_keeps_empty_and_na_strings_by_default",
+			opts:           nil,
+			expected:       []interface{}{"alice", "", "NA"},
+			expectedCounts: map[string]int{},
+		},
+		{
+			name:           "test_applies_custom_null_tokens_to_strings",
+			opts:           []Option{WithNullTokens(""), WithDefaultNullPolicy(NullPolicy{Action: NullMark})},
+			expected:       []interface{}{"alice", nil, "NA"},
+			expectedCounts: map[string]int{"name": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSource("test", "test_8.csv", nil, tt.opts...)
+			assert.NoError(t, err)
+			assert.Equal(t, column.String, s.Types()["name"])
+			assert.Equal(t, tt.expectedCounts, s.NullCounts())
+			col := s.data["name"]
+			assert.Equal(t, len(tt.expected), col.Len())
+			for i, v := range tt.expected {
+				if v == nil {
+					assert.True(t, col.IsNull(i))
+					continue
+				}
+				assert.Equal(t, v, col.Values[i])
+			}
+		})
+	}
+}
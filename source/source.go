@@ -4,11 +4,13 @@ package source
 import (
 	"encoding/csv"
 	"fmt"
-	"math"
+	"io"
 	"os"
 	"path"
-	"strconv"
+	"sort"
+	"strings"
 
+	"github.com/flaviuvadan/pipe-flow/column"
 	"github.com/flaviuvadan/pipe-flow/pipe"
 )
 
@@ -17,18 +19,109 @@ const ColIndex = 0
 
 // Source represents the beginning state of a pipeline
 type Source struct {
-	Description string                // Description of the source
-	Pipes       map[string]*pipe.Pipe // mapping of CSV column titles to the Pipes that will operate on the columns
-	filename    string                // filename to the CSV file to be read by the source, in the current working directory
-	data        map[string][]float64  // mapping of CSV column titles to the column data
+	Description string                    // Description of the source
+	Pipes       map[string]*pipe.Pipe     // mapping of CSV column titles to the Pipes that will operate on the columns
+	filename    string                    // filename to the CSV file to be read by the source, in the current working directory
+	columns     []string                  // CSV column titles in the order of the CSV header
+	types       map[string]column.Type    // mapping of CSV column titles to their declared or inferred types
+	inferred    map[string]bool           // CSV column titles whose type is inferred rather than declared
+	data        map[string]*column.Column // mapping of CSV column titles to the column data
+	rows        int                       // number of data rows read so far
+	bytesRead   int64                     // number of bytes read from the CSV file so far
+	chunkSize   int                       // number of rows per chunk when streaming, 0 means the whole file is read at once
+	file        *os.File                  // file the source streams from, only open in streaming mode
+	reader      *csv.Reader               // reader of the file the source streams from
+	unmapped    Unmapped                  // what to do with the CSV columns no pipe operates on
+	fanOut      map[string][]*pipe.Pipe   // mapping of CSV column titles to the pipes bound to them besides Pipes
+	rowPipes    []*pipe.Pipe              // pipes that flow records of several CSV columns, see pipe.NewRowPipe
+
+	nullTokens        map[string]bool        // CSV cells that are treated as null
+	defaultTokens     bool                   // whether nullTokens are DefaultNullTokens, which String columns ignore
+	nullPolicies      map[string]NullPolicy  // mapping of CSV column titles to their null policies
+	defaultNullPolicy NullPolicy             // null policy of the columns that do not have their own
+	nullCounts        map[string]int         // mapping of CSV column titles to the number of null cells read
+	last              map[string]interface{} // mapping of CSV column titles to their last valid value, for forward fills
+}
+
+// Option configures optional behaviour of a Source
+type Option func(*Source)
+
+// WithChunkSize makes the source stream the CSV file in chunks of n rows rather than reading it whole, see Next
+func WithChunkSize(n int) Option {
+	return func(s *Source) {
+		s.chunkSize = n
+	}
+}
+
+// WithPipes binds the given pipes to a CSV column on top of the pipe of that column in Pipes, if any, so that a column
+// can flow through any number of pipes. Pipes bound to the same column must have distinct output names, see
+// pipe.SetOutputName
+func WithPipes(col string, pps ...*pipe.Pipe) Option {
+	return func(s *Source) {
+		s.fanOut[col] = append(s.fanOut[col], pps...)
+	}
+}
+
+// WithRowPipes binds row pipes to the source, every row pipe receives the CSV columns it declares as inputs, see
+// pipe.NewRowPipe. Group by pipes are bound the same way, see pipe.NewGroupByPipe
+func WithRowPipes(pps ...*pipe.Pipe) Option {
+	return func(s *Source) {
+		s.rowPipes = append(s.rowPipes, pps...)
+	}
+}
+
+// Unmapped tells what a source does with the CSV columns that no pipe operates on
+type Unmapped int
+
+const (
+	Ignore      Unmapped = iota // unmapped columns are read but not passed on, this is the default
+	PassThrough                 // unmapped columns are passed through to the sink unchanged
+)
+
+// WithUnmapped sets what the source does with the CSV columns that no pipe operates on
+func WithUnmapped(u Unmapped) Option {
+	return func(s *Source) {
+		s.unmapped = u
+	}
+}
+
+// WithTypes declares the types of the given CSV columns, the types of the other columns are inferred from their values
+func WithTypes(types map[string]column.Type) Option {
+	return func(s *Source) {
+		for c, t := range types {
+			s.types[c] = t
+		}
+	}
 }
 
 // New returns a new instance of a Source
-func NewSource(dsc, file string, pps map[string]*pipe.Pipe) (*Source, error) {
+func NewSource(dsc, file string, pps map[string]*pipe.Pipe, opts ...Option) (*Source, error) {
 	s := &Source{
 		Description: dsc,
 		filename:    file,
 		Pipes:       pps,
+		types:       map[string]column.Type{},
+		inferred:    map[string]bool{},
+		fanOut:      map[string][]*pipe.Pipe{},
+
+		nullPolicies: map[string]NullPolicy{},
+		nullCounts:   map[string]int{},
+		last:         map[string]interface{}{},
+	}
+	WithNullTokens(DefaultNullTokens...)(s)
+	s.defaultTokens = true
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.Streaming() {
+		if err := s.open(); err != nil {
+			return nil, err
+		}
+		if err := s.checkPipes(); err != nil {
+			_ = s.Close()
+			return nil, err
+		}
+		return s, nil
 	}
 	if err := s.read(); err != nil {
 		return nil, err
@@ -41,21 +134,244 @@ func NewSource(dsc, file string, pps map[string]*pipe.Pipe) (*Source, error) {
 
 // setPipeData sets the input data sources for each pipe
 func (s *Source) setPipeData() error {
-	if len(s.Pipes) == 0 {
+	bindings := s.Bindings()
+	if len(bindings) == 0 {
 		return nil
 	}
 	if len(s.data) == 0 {
 		return nil
 	}
-	if len(s.data) != len(s.Pipes) {
-		return fmt.Errorf("%v pipe/s do/es not have a data/data source/s", math.Abs(float64(len(s.data)-len(s.Pipes))))
+	if err := s.checkPipes(); err != nil {
+		return err
 	}
-	for k, v := range s.data {
-		s.Pipes[k].SetInput(map[string][]float64{k: v})
+	for _, b := range bindings {
+		in := make(map[string]*column.Column, len(b.Columns))
+		for _, c := range b.Columns {
+			in[c] = s.data[c]
+		}
+		b.Pipe.SetTypedInput(in)
 	}
 	return nil
 }
 
+// checkPipes checks that every pipe is bound to a column of the CSV file, that no pipe is bound to several columns and
+// that no two pipes output columns of the same name
+func (s *Source) checkPipes() error {
+	cols := map[string]bool{}
+	for _, c := range s.columns {
+		cols[c] = true
+	}
+	var missing []string
+	for k := range s.Pipes {
+		if !cols[k] {
+			missing = append(missing, k)
+		}
+	}
+	for k := range s.fanOut {
+		if _, ok := s.Pipes[k]; !ok && !cols[k] {
+			missing = append(missing, k)
+		}
+	}
+	if len(missing) != 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("pipe/s (%s) reference/s column/s that do/es not exist in file: %s", strings.Join(missing, ", "), s.filename)
+	}
+	for _, p := range s.rowPipes {
+		if p.GetInputColumns() == nil {
+			return fmt.Errorf("pipe (%s) is not a row pipe", p.Description)
+		}
+		for _, c := range p.GetInputColumns() {
+			if !cols[c] {
+				return fmt.Errorf("row pipe (%s) references column (%s) that does not exist in file: %s", p.Description, c, s.filename)
+			}
+		}
+	}
+
+	bound := map[*pipe.Pipe]string{}
+	outputs := map[string]*pipe.Pipe{}
+	if s.PassesThrough() {
+		for _, c := range s.Unmapped() {
+			outputs[c] = nil
+		}
+	}
+	for _, b := range s.Bindings() {
+		cols := strings.Join(b.Columns, ", ")
+		if c, ok := bound[b.Pipe]; ok {
+			return fmt.Errorf("pipe (%s) is bound to columns (%s) and (%s), a pipe can only be bound once", b.Pipe.Description, c, cols)
+		}
+		bound[b.Pipe] = cols
+		for _, name := range b.Pipe.GetOutputNames(b.Columns) {
+			if o, ok := outputs[name]; ok {
+				if o == nil {
+					return fmt.Errorf("pipe (%s) outputs column (%s) that is passed through", b.Pipe.Description, name)
+				}
+				return fmt.Errorf("pipes (%s) and (%s) both output column (%s), set distinct output names", o.Description, b.Pipe.Description, name)
+			}
+			outputs[name] = b.Pipe
+		}
+	}
+	return nil
+}
+
+// Binding binds a pipe to the CSV columns that flow through it
+type Binding struct {
+	Columns []string   // CSV column titles, a single one unless the pipe is a row pipe
+	Pipe    *pipe.Pipe // pipe the columns flow through
+}
+
+// Bindings returns the pipes of the source along with their columns, in the order of the CSV header. The pipe of a
+// column in Pipes comes before the ones bound with WithPipes, columns that are not part of the header follow in
+// alphabetical order and row pipes come last
+func (s *Source) Bindings() []Binding {
+	var bindings []Binding
+	bind := func(c string) {
+		if p, ok := s.Pipes[c]; ok {
+			bindings = append(bindings, Binding{Columns: []string{c}, Pipe: p})
+		}
+		for _, p := range s.fanOut[c] {
+			bindings = append(bindings, Binding{Columns: []string{c}, Pipe: p})
+		}
+	}
+	seen := map[string]bool{}
+	for _, c := range s.columns {
+		bind(c)
+		seen[c] = true
+	}
+	var rest []string
+	for c := range s.Pipes {
+		if !seen[c] {
+			rest = append(rest, c)
+			seen[c] = true
+		}
+	}
+	for c := range s.fanOut {
+		if !seen[c] {
+			rest = append(rest, c)
+			seen[c] = true
+		}
+	}
+	sort.Strings(rest)
+	for _, c := range rest {
+		bind(c)
+	}
+	for _, p := range s.rowPipes {
+		bindings = append(bindings, Binding{Columns: p.GetInputColumns(), Pipe: p})
+	}
+	return bindings
+}
+
+// Unmapped returns the CSV column titles that no pipe operates on, in the order of the CSV header
+func (s *Source) Unmapped() []string {
+	inRows := map[string]bool{}
+	for _, p := range s.rowPipes {
+		for _, c := range p.GetInputColumns() {
+			inRows[c] = true
+		}
+	}
+	var cols []string
+	for _, c := range s.columns {
+		if _, ok := s.Pipes[c]; !ok && len(s.fanOut[c]) == 0 && !inRows[c] {
+			cols = append(cols, c)
+		}
+	}
+	return cols
+}
+
+// OutputColumns returns the names of the columns the source feeds the sink with, in the order of the CSV header: the
+// output columns of the pipes bound to every column, or the column itself when it is passed through. The output
+// columns of row pipes follow
+func (s *Source) OutputColumns() []string {
+	passed := map[string]bool{}
+	if s.PassesThrough() {
+		for _, c := range s.Unmapped() {
+			passed[c] = true
+		}
+	}
+	var cols []string
+	bindings := s.Bindings()
+	for _, c := range s.columns {
+		if passed[c] {
+			cols = append(cols, c)
+		}
+		for _, b := range bindings {
+			if len(b.Columns) == 1 && b.Pipe.GetInputColumns() == nil && b.Columns[0] == c {
+				cols = append(cols, b.Pipe.GetOutputNames(b.Columns)...)
+			}
+		}
+	}
+	for _, p := range s.rowPipes {
+		cols = append(cols, p.GetOutputNames(nil)...)
+	}
+	return cols
+}
+
+// PassThrough returns the columns that no pipe operates on when the source passes them through to the sink, nil
+// otherwise. Streaming sources pass their unmapped columns through chunk by chunk instead, see Next
+func (s *Source) PassThrough() map[string]*column.Column {
+	if s.unmapped != PassThrough || s.data == nil {
+		return nil
+	}
+	cols := map[string]*column.Column{}
+	for _, c := range s.Unmapped() {
+		cols[c] = s.data[c]
+	}
+	return cols
+}
+
+// PassesThrough tells whether the columns no pipe operates on are passed through to the sink unchanged
+func (s *Source) PassesThrough() bool {
+	return s.unmapped == PassThrough
+}
+
+// Types returns the declared or inferred types of the CSV columns
+func (s *Source) Types() map[string]column.Type {
+	return s.types
+}
+
+// Columns returns the CSV column titles in the order of the CSV header
+func (s *Source) Columns() []string {
+	return s.columns
+}
+
+// OrderedPipes returns the pipes of the source in the order of their Bindings
+func (s *Source) OrderedPipes() []*pipe.Pipe {
+	bindings := s.Bindings()
+	ordered := make([]*pipe.Pipe, 0, len(bindings))
+	for _, b := range bindings {
+		ordered = append(ordered, b.Pipe)
+	}
+	return ordered
+}
+
+// File returns the name of the CSV file the source reads, in the current working directory
+func (s *Source) File() string {
+	return s.filename
+}
+
+// Rows returns the number of data rows the source read so far, rows that null policies dropped included
+func (s *Source) Rows() int {
+	return s.rows
+}
+
+// BytesRead returns the number of bytes the source read from its CSV file so far, the ones of the joined sources for
+// a joined source
+func (s *Source) BytesRead() int64 {
+	return s.bytesRead
+}
+
+// countingReader counts the bytes read from the reader it wraps
+type countingReader struct {
+	r io.Reader // the wrapped reader
+	n *int64    // number of bytes read so far
+}
+
+// Read reads from the wrapped reader and counts the bytes that were read
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	*c.n += int64(n)
+	return n, err
+}
+
 // read reads in the CSV formatted file passed as filename to the Source initializer
 func (s *Source) read() error {
 	cwd, err := os.Getwd()
@@ -64,8 +380,7 @@ func (s *Source) read() error {
 	}
 	f, err := os.Open(path.Join(cwd, s.filename))
 	if err != nil {
-		fmt.Println(err)
-		return fmt.Errorf("failed to open the file located at: %s", s.filename)
+		return fmt.Errorf("failed to open the file located at: %s, err: %v", s.filename, err)
 	}
 
 	defer func() {
@@ -74,7 +389,7 @@ func (s *Source) read() error {
 		}
 	}()
 
-	r := csv.NewReader(f)
+	r := csv.NewReader(&countingReader{r: f, n: &s.bytesRead})
 	content, err := r.ReadAll()
 	if err != nil {
 		return fmt.Errorf("failed to read the content of the file located at: %s", s.filename)
@@ -84,18 +399,91 @@ func (s *Source) read() error {
 		return fmt.Errorf("empty file provided")
 	}
 
-	cols := content[ColIndex]
-	s.data = map[string][]float64{}
-	for i, c := range cols {
-		colData := make([]float64, len(content)-1)
-		for j, r := range content[1:] {
-			v, err := strconv.ParseFloat(r[i], 64)
+	s.columns = content[ColIndex]
+	data, err := s.parse(content[1:])
+	if err != nil {
+		return err
+	}
+	s.data = data
+	return nil
+}
+
+// parse parses the given CSV rows into typed columns, null cells are handled according to the null policy of their
+// column. Columns without a declared type get the type inferred from the valid values of the first rows they are
+// parsed from, e.g the first chunk of a streaming source, and Int columns are widened to Float by later chunks that need it
+func (s *Source) parse(rows [][]string) (map[string]*column.Column, error) {
+	texts := s.textColumns(rows)
+	for _, r := range rows {
+		for i, c := range s.columns {
+			if s.isNull(r[i], texts[c]) {
+				s.nullCounts[c]++
+			}
+		}
+	}
+	offset := s.rows
+	s.rows += len(rows)
+	rows, indices := s.dropNullRows(rows, texts)
+
+	data := make(map[string]*column.Column, len(s.columns))
+	for i, c := range s.columns {
+		raws := make([]string, 0, len(rows))
+		for _, r := range rows {
+			if !s.isNull(r[i], texts[c]) {
+				raws = append(raws, r[i])
+			}
+		}
+		t, ok := s.types[c]
+		if !ok {
+			t = column.Infer(raws)
+			if t == column.Int && s.nullPolicy(c).Action == NullNaN {
+				t = column.Float
+			}
+			s.types[c] = t
+			s.inferred[c] = true
+		} else if t == column.Int && s.inferred[c] && column.Infer(raws) == column.Float {
+			t = column.Float
+			s.types[c] = t
+			if v, ok := s.last[c].(int64); ok {
+				s.last[c] = float64(v)
+			}
+		}
+		col := column.New(c, t, make([]interface{}, 0, len(rows)))
+		for j, r := range rows {
+			var v interface{}
+			var err error
+			if s.isNull(r[i], texts[c]) {
+				v, err = s.fillNull(c, t, offset+indices[j])
+			} else if v, err = t.Parse(r[i]); err != nil {
+				err = fmt.Errorf("failed to parse value on row %d of col (%s), err: %v", offset+indices[j], c, err)
+			} else {
+				s.last[c] = v
+			}
 			if err != nil {
-				return fmt.Errorf("failed to parse row value to float64: %v", r[i])
+				return nil, err
 			}
-			colData[j] = v
+			col.Push(v)
 		}
-		s.data[c] = colData
+		data[c] = col
 	}
-	return nil
+	return data, nil
+}
+
+// textColumns tells which columns hold strings: the ones declared or inferred as String and, for the columns without a
+// type yet, the ones whose cells of the given rows, null tokens excluded, are inferred as String
+func (s *Source) textColumns(rows [][]string) map[string]bool {
+	texts := make(map[string]bool, len(s.columns))
+	for i, c := range s.columns {
+		if t, ok := s.types[c]; ok {
+			texts[c] = t == column.String
+			continue
+		}
+		raws := make([]string, 0, len(rows))
+		for _, r := range rows {
+			if !s.nullTokens[r[i]] {
+				raws = append(raws, r[i])
+			}
+		}
+		texts[c] = column.Infer(raws) == column.String
+	}
+	return texts
 }
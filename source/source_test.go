@@ -1,14 +1,24 @@
 package source
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"path"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/flaviuvadan/pipe-flow/column"
 	"github.com/flaviuvadan/pipe-flow/pipe"
 )
 
+// badPath returns the path of the missing file the tests try to open
+func badPath() string {
+	cwd, _ := os.Getwd()
+	return path.Join(cwd, "bad")
+}
+
 func TestNewSource(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -44,7 +54,7 @@ func TestNewSource(t *testing.T) {
 			description: "test",
 			path:        "bad",
 			pipes:       nil,
-			expectedErr: fmt.Errorf("failed to open the file located at: bad"),
+			expectedErr: fmt.Errorf("failed to open the file located at: bad, err: open %s: no such file or directory", badPath()),
 		},
 		{
 			name:        "test_source_returns_err_when_csv_not_valid",
@@ -67,3 +77,255 @@ func TestNewSource(t *testing.T) {
 		})
 	}
 }
+
+func TestSource_OrderedPipes(t *testing.T) {
+	t.Parallel()
+	a := pipe.NewSingleOpsPipe("a", nil)
+	b := pipe.NewSingleOpsPipe("b", nil)
+	c := pipe.NewSingleOpsPipe("c", nil)
+	s, err := NewSource("test", "test_5.csv", map[string]*pipe.Pipe{"a": a, "b": b, "c": c})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c", "a", "b"}, s.Columns())
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, []*pipe.Pipe{c, a, b}, s.OrderedPipes())
+	}
+}
+
+func TestNewSource_Types(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name          string
+		types         map[string]column.Type
+		expectedTypes map[string]column.Type
+		expectedErr   error
+	}{
+		{
+			name: "test_infers_column_types",
+			expectedTypes: map[string]column.Type{
+				"id":     column.Int,
+				"name":   column.String,
+				"active": column.Bool,
+				"joined": column.Time,
+				"score":  column.Float,
+			},
+		},
+		{
+			name:  "test_uses_declared_column_types",
+			types: map[string]column.Type{"id": column.String, "score": column.Float},
+			expectedTypes: map[string]column.Type{
+				"id":     column.String,
+				"name":   column.String,
+				"active": column.Bool,
+				"joined": column.Time,
+				"score":  column.Float,
+			},
+		},
+		{
+			name:        "test_returns_err_when_values_do_not_match_declared_type",
+			types:       map[string]column.Type{"name": column.Int},
+			expectedErr: fmt.Errorf("failed to parse value on row 0 of col (name), err: failed to parse row value to int64: ann"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSource("test", "test_6.csv", nil, WithTypes(tt.types))
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedTypes, s.Types())
+			for c, typ := range tt.expectedTypes {
+				for _, v := range s.data[c].Values {
+					assert.NoError(t, typ.Check(v))
+				}
+			}
+		})
+	}
+}
+
+func TestNewSource_Subset(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name             string
+		pipes            []string
+		unmapped         Unmapped
+		expectedUnmapped []string
+		expectedPassed   []string
+		expectedErr      error
+	}{
+		{
+			name:             "test_attaches_pipes_to_a_subset_of_columns",
+			pipes:            []string{"a"},
+			expectedUnmapped: []string{"b", "c"},
+		},
+		{
+			name:             "test_passes_unmapped_columns_through",
+			pipes:            []string{"b"},
+			unmapped:         PassThrough,
+			expectedUnmapped: []string{"a", "c"},
+			expectedPassed:   []string{"a", "c"},
+		},
+		{
+			name:        "test_returns_err_naming_pipes_of_missing_columns",
+			pipes:       []string{"a", "z", "d"},
+			expectedErr: fmt.Errorf("pipe/s (d, z) reference/s column/s that do/es not exist in file: test_3.csv"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pps := map[string]*pipe.Pipe{}
+			for _, c := range tt.pipes {
+				pps[c] = pipe.NewSingleOpsPipe(c, nil)
+			}
+			s, err := NewSource("test", "test_3.csv", pps, WithUnmapped(tt.unmapped))
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedUnmapped, s.Unmapped())
+			for _, c := range tt.pipes {
+				assert.Equal(t, s.data[c], pps[c].GetTypedInput()[c])
+			}
+			passed := s.PassThrough()
+			assert.Len(t, passed, len(tt.expectedPassed))
+			for _, c := range tt.expectedPassed {
+				assert.Equal(t, s.data[c], passed[c])
+			}
+		})
+	}
+}
+
+func TestWithPipes(t *testing.T) {
+	t.Parallel()
+	named := func(ds, out string) *pipe.Pipe {
+		p := pipe.NewSingleOpsPipe(ds, nil)
+		p.SetOutputName(out)
+		return p
+	}
+	shared := pipe.NewSingleOpsPipe("shared", nil)
+	tests := []struct {
+		name            string
+		pipes           map[string]*pipe.Pipe
+		fanOut          map[string][]*pipe.Pipe
+		unmapped        Unmapped
+		expectedOutputs []string
+		expectedErr     error
+	}{
+		{
+			name:            "test_binds_column_to_many_pipes",
+			pipes:           map[string]*pipe.Pipe{"a": pipe.NewSingleOpsPipe("a", nil)},
+			fanOut:          map[string][]*pipe.Pipe{"a": {named("a_sum", "a_sum"), named("a_plus1", "a_plus1")}, "c": {named("c_sum", "c_sum")}},
+			unmapped:        PassThrough,
+			expectedOutputs: []string{"a", "a_sum", "a_plus1", "b", "c_sum"},
+		},
+		{
+			name:        "test_returns_err_on_duplicate_output_names",
+			pipes:       map[string]*pipe.Pipe{"a": pipe.NewSingleOpsPipe("first", nil)},
+			fanOut:      map[string][]*pipe.Pipe{"a": {pipe.NewSingleOpsPipe("second", nil)}},
+			expectedErr: fmt.Errorf("pipes (first) and (second) both output column (a), set distinct output names"),
+		},
+		{
+			name:        "test_returns_err_on_output_name_of_passed_through_column",
+			fanOut:      map[string][]*pipe.Pipe{"a": {named("a_pipe", "b")}},
+			unmapped:    PassThrough,
+			expectedErr: fmt.Errorf("pipe (a_pipe) outputs column (b) that is passed through"),
+		},
+		{
+			name:        "test_returns_err_on_pipe_bound_to_several_columns",
+			fanOut:      map[string][]*pipe.Pipe{"a": {shared}, "b": {shared}},
+			expectedErr: fmt.Errorf("pipe (shared) is bound to columns (a) and (b), a pipe can only be bound once"),
+		},
+		{
+			name:        "test_returns_err_on_missing_fan_out_column",
+			fanOut:      map[string][]*pipe.Pipe{"z": {named("z_sum", "z_sum")}},
+			expectedErr: fmt.Errorf("pipe/s (z) reference/s column/s that do/es not exist in file: test_3.csv"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := []Option{WithUnmapped(tt.unmapped)}
+			for c, pps := range tt.fanOut {
+				opts = append(opts, WithPipes(c, pps...))
+			}
+			s, err := NewSource("test", "test_3.csv", tt.pipes, opts...)
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedOutputs, s.OutputColumns())
+			for _, b := range s.Bindings() {
+				assert.Equal(t, s.data[b.Columns[0]], b.Pipe.GetTypedInput()[b.Columns[0]])
+			}
+		})
+	}
+}
+
+func TestWithRowPipes(t *testing.T) {
+	t.Parallel()
+	sum := func(_ context.Context, r pipe.Record) (pipe.Record, error) {
+		return pipe.Record{"sum": r["a"].(int64) + r["b"].(int64)}, nil
+	}
+	tests := []struct {
+		name             string
+		pipes            []*pipe.Pipe
+		expectedUnmapped []string
+		expectedOutputs  []string
+		expectedErr      error
+	}{
+		{
+			name:             "test_binds_row_pipe_to_its_input_columns",
+			pipes:            []*pipe.Pipe{pipe.NewRowPipe("sum", []string{"a", "b"}, map[string]column.Type{"sum": column.Int}, sum)},
+			expectedUnmapped: []string{"c"},
+			expectedOutputs:  []string{"sum"},
+		},
+		{
+			name:        "test_returns_err_on_missing_input_column",
+			pipes:       []*pipe.Pipe{pipe.NewRowPipe("sum", []string{"a", "z"}, map[string]column.Type{"sum": column.Int}, sum)},
+			expectedErr: fmt.Errorf("row pipe (sum) references column (z) that does not exist in file: test_3.csv"),
+		},
+		{
+			name:        "test_returns_err_on_pipe_that_is_not_a_row_pipe",
+			pipes:       []*pipe.Pipe{pipe.NewSingleOpsPipe("single", nil)},
+			expectedErr: fmt.Errorf("pipe (single) is not a row pipe"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSource("test", "test_3.csv", nil, WithRowPipes(tt.pipes...))
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedUnmapped, s.Unmapped())
+			assert.Equal(t, tt.expectedOutputs, s.OutputColumns())
+			in := tt.pipes[0].GetTypedInput()
+			assert.Equal(t, map[string]*column.Column{"a": s.data["a"], "b": s.data["b"]}, in)
+		})
+	}
+}
+
+func TestSource_BytesRead(t *testing.T) {
+	t.Parallel()
+	for _, chunkSize := range []int{0, 2} {
+		s, err := NewSource("test", "test_3.csv", nil, WithChunkSize(chunkSize))
+		assert.NoError(t, err)
+		// a streaming source reads its rows chunk by chunk
+		for err == nil && s.Streaming() {
+			_, err = s.Next()
+		}
+		assert.Equal(t, "test_3.csv", s.File())
+		assert.Equal(t, 3, s.Rows())
+		assert.Equal(t, int64(23), s.BytesRead())
+		assert.NoError(t, s.Close())
+	}
+
+	left, _ := NewSource("left", "test_join_left.csv", nil)
+	right, _ := NewSource("right", "test_join_right.csv", nil)
+	joined, err := NewJoinedSource("joined", left, right, Join{Kind: InnerJoin, On: []string{"id"}}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(52), joined.BytesRead())
+}
@@ -0,0 +1,79 @@
+package source
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/flaviuvadan/pipe-flow/column"
+)
+
+// Streaming tells whether the source streams its CSV file in chunks rather than reading it whole
+func (s *Source) Streaming() bool {
+	return s.chunkSize > 0
+}
+
+// open opens the CSV file of a streaming source and reads its header
+func (s *Source) open() error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get the current working directory")
+	}
+	f, err := os.Open(path.Join(cwd, s.filename))
+	if err != nil {
+		return fmt.Errorf("failed to open the file located at: %s, err: %v", s.filename, err)
+	}
+	r := csv.NewReader(&countingReader{r: f, n: &s.bytesRead})
+	header, err := r.Read()
+	if err == io.EOF {
+		_ = f.Close()
+		return fmt.Errorf("empty file provided")
+	}
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to read the content of the file located at: %s", s.filename)
+	}
+	s.file = f
+	s.reader = r
+	s.columns = header
+	return nil
+}
+
+// Next reads the next chunk of rows of a streaming source and returns it as a mapping of CSV column titles to the
+// chunk data. io.EOF is returned once all the rows were read
+func (s *Source) Next() (map[string]*column.Column, error) {
+	if s.reader == nil {
+		return nil, fmt.Errorf("source (%s) is not streaming", s.Description)
+	}
+	var rows [][]string
+	for len(rows) < s.chunkSize {
+		r, err := s.reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the content of the file located at: %s", s.filename)
+		}
+		rows = append(rows, r)
+	}
+	if len(rows) == 0 {
+		return nil, io.EOF
+	}
+	return s.parse(rows)
+}
+
+// Close closes the CSV file of a streaming source, it is a no-op for sources that are not streaming
+func (s *Source) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	s.reader = nil
+	if err != nil {
+		return fmt.Errorf("failed to close file (%s) after reading content, err: %v", s.filename, err)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package source
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flaviuvadan/pipe-flow/column"
+	"github.com/flaviuvadan/pipe-flow/pipe"
+)
+
+func TestSource_Next(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name           string
+		path           string
+		chunkSize      int
+		expectedChunks []map[string][]float64
+		expectedErr    error
+	}{
+		{
+			name:      "test_streams_single_row_chunks",
+			path:      "test_3.csv",
+			chunkSize: 1,
+			expectedChunks: []map[string][]float64{
+				{"a": {1}, "b": {4}, "c": {7}},
+				{"a": {2}, "b": {5}, "c": {8}},
+				{"a": {3}, "b": {6}, "c": {9}},
+			},
+		},
+		{
+			name:      "test_streams_whole_file_in_larger_chunk",
+			path:      "test_3.csv",
+			chunkSize: 10,
+			expectedChunks: []map[string][]float64{
+				{"a": {1, 2, 3}, "b": {4, 5, 6}, "c": {7, 8, 9}},
+			},
+		},
+		{
+			name:        "test_returns_err_on_empty_file",
+			path:        "test_1.csv",
+			chunkSize:   1,
+			expectedErr: fmt.Errorf("empty file provided"),
+		},
+		{
+			name:        "test_returns_err_when_file_not_found",
+			path:        "bad",
+			chunkSize:   1,
+			expectedErr: fmt.Errorf("failed to open the file located at: bad, err: open %s: no such file or directory", badPath()),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSource("test", tt.path, nil, WithChunkSize(tt.chunkSize))
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, s.Streaming())
+			assert.Equal(t, []string{"a", "b", "c"}, s.Columns())
+			for _, expected := range tt.expectedChunks {
+				chunk, err := s.Next()
+				assert.NoError(t, err)
+				assert.Equal(t, expected, floats(chunk))
+			}
+			_, err = s.Next()
+			assert.Equal(t, io.EOF, err)
+			assert.NoError(t, s.Close())
+		})
+	}
+}
+
+func TestSource_Next_WidensInferredInts(t *testing.T) {
+	t.Parallel()
+	s, err := NewSource("test", "test_9.csv", nil, WithChunkSize(2))
+	assert.NoError(t, err)
+	chunk, err := s.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, column.Int, chunk["a"].Type)
+	chunk, err = s.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, column.Float, chunk["a"].Type)
+	assert.Equal(t, []interface{}{2.5}, chunk["a"].Values)
+	assert.Equal(t, column.Float, s.Types()["a"])
+	assert.NoError(t, s.Close())
+
+	// declared types are not widened
+	s, err = NewSource("test", "test_9.csv", nil, WithChunkSize(2), WithTypes(map[string]column.Type{"a": column.Int}))
+	assert.NoError(t, err)
+	_, err = s.Next()
+	assert.NoError(t, err)
+	_, err = s.Next()
+	assert.EqualError(t, err, "failed to parse value on row 2 of col (a), err: failed to parse row value to int64: 2.5")
+	assert.NoError(t, s.Close())
+}
+
+func TestSource_Next_NotStreaming(t *testing.T) {
+	t.Parallel()
+	s, err := NewSource("test", "test_3.csv", map[string]*pipe.Pipe{
+		"a": pipe.NewSingleOpsPipe("a", nil),
+		"b": pipe.NewSingleOpsPipe("b", nil),
+		"c": pipe.NewSingleOpsPipe("c", nil),
+	})
+	assert.NoError(t, err)
+	assert.False(t, s.Streaming())
+	_, err = s.Next()
+	assert.EqualError(t, err, "source (test) is not streaming")
+	assert.NoError(t, s.Close())
+}
+
+// floats converts typed chunk columns into float64 values for comparison
+func floats(chunk map[string]*column.Column) map[string][]float64 {
+	m := map[string][]float64{}
+	for k, c := range chunk {
+		m[k], _ = c.Floats()
+	}
+	return m
+}
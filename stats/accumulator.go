@@ -0,0 +1,130 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+)
+
+// SumAccumulator is an online sum, with compensated (Kahan) summation, that can be used as the pipe.Accumulator of a
+// streaming source
+type SumAccumulator struct {
+	k kahan
+}
+
+// Add adds a value to the sum
+func (a *SumAccumulator) Add(v float64) error {
+	a.k.add(v)
+	return nil
+}
+
+// Result returns the sum of the values added so far
+func (a *SumAccumulator) Result() (float64, error) {
+	return a.k.result(), nil
+}
+
+// MeanAccumulator is an online mean, with Welford's algorithm, that can be used as the pipe.Accumulator of a streaming
+// source
+type MeanAccumulator struct {
+	w welford
+}
+
+// Add adds a value to the mean
+func (a *MeanAccumulator) Add(v float64) error {
+	a.w.add(v)
+	return nil
+}
+
+// Result returns the mean of the values added so far
+func (a *MeanAccumulator) Result() (float64, error) {
+	if a.w.n == 0 {
+		return 0, fmt.Errorf("cannot average no values")
+	}
+	return a.w.mean, nil
+}
+
+// VarianceAccumulator is an online variance, with Welford's algorithm, that can be used as the pipe.Accumulator of a
+// streaming source
+type VarianceAccumulator struct {
+	Sample bool // whether the result is the sample variance rather than the population variance
+	StdDev bool // whether the result is the standard deviation rather than the variance
+	w      welford
+}
+
+// Add adds a value to the variance
+func (a *VarianceAccumulator) Add(v float64) error {
+	a.w.add(v)
+	return nil
+}
+
+// Result returns the variance, or standard deviation, of the values added so far
+func (a *VarianceAccumulator) Result() (float64, error) {
+	if a.w.n == 0 || (a.Sample && a.w.n < 2) {
+		return 0, fmt.Errorf("cannot take the variance of %d values", a.w.n)
+	}
+	v := a.w.variance(a.Sample)
+	if a.StdDev {
+		return math.Sqrt(v), nil
+	}
+	return v, nil
+}
+
+// kahan is a compensated sum, it keeps track of the low order bits that are lost when adding values of different
+// magnitudes (Neumaier's variant of Kahan summation)
+type kahan struct {
+	sum float64 // the running sum
+	c   float64 // the compensation of the lost low order bits
+}
+
+// add adds a value to the sum, sums that are not finite are not compensated as the compensation of an infinite sum is
+// NaN, e.g Inf - Inf
+func (k *kahan) add(v float64) {
+	t := k.sum + v
+	if math.IsInf(t, 0) || math.IsNaN(t) {
+		k.sum = t
+		return
+	}
+	if math.Abs(k.sum) >= math.Abs(v) {
+		k.c += (k.sum - t) + v
+	} else {
+		k.c += (v - t) + k.sum
+	}
+	k.sum = t
+}
+
+// result returns the compensated sum, or the plain sum when it is not finite
+func (k *kahan) result() float64 {
+	if math.IsInf(k.sum, 0) || math.IsNaN(k.sum) {
+		return k.sum
+	}
+	return k.sum + k.c
+}
+
+// welford is Welford's online algorithm for the mean and variance, which avoids the catastrophic cancellation of the
+// naive sum of squares
+type welford struct {
+	n    int     // number of values added so far
+	mean float64 // the running mean
+	m2   float64 // the running sum of squared differences from the mean
+}
+
+// add adds a value to the mean and variance, once an infinite value is added the mean is the plain sum of the values,
+// which keeps its sign, or NaN for infinite values of both signs, and the variance is NaN
+func (w *welford) add(v float64) {
+	w.n++
+	if math.IsInf(v, 0) || math.IsInf(w.mean, 0) {
+		w.mean += v
+		w.m2 = math.NaN()
+		return
+	}
+	d := v - w.mean
+	w.mean += d / float64(w.n)
+	w.m2 += d * (v - w.mean)
+}
+
+// variance returns the population variance, or the sample variance, of the values added so far
+func (w *welford) variance(sample bool) float64 {
+	if sample {
+		return w.m2 / float64(w.n-1)
+	}
+	return w.m2 / float64(w.n)
+}
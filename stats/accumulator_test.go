@@ -0,0 +1,47 @@
+package stats
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// accumulator is the interface of pipe.Accumulator, the accumulators of the package implement it
+type accumulator interface {
+	Add(v float64) error
+	Result() (float64, error)
+}
+
+func TestAccumulators(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		acc         accumulator
+		values      []float64
+		expected    float64
+		expectedErr error
+	}{
+		{name: "test_sum", acc: &SumAccumulator{}, values: []float64{1e16, 1, -1e16}, expected: 1},
+		{name: "test_mean", acc: &MeanAccumulator{}, values: []float64{1, 2, 6}, expected: 3},
+		{name: "test_variance", acc: &VarianceAccumulator{}, values: []float64{4, 1, 3, 1, 6}, expected: 3.6},
+		{name: "test_sample_variance", acc: &VarianceAccumulator{Sample: true}, values: []float64{4, 1, 3, 1, 6}, expected: 4.5},
+		{name: "test_std_dev", acc: &VarianceAccumulator{StdDev: true}, values: []float64{2, 4, 4, 4, 5, 5, 7, 9}, expected: 2},
+		{name: "test_mean_of_no_values", acc: &MeanAccumulator{}, expectedErr: fmt.Errorf("cannot average no values")},
+		{name: "test_sample_variance_of_a_value", acc: &VarianceAccumulator{Sample: true}, values: []float64{1}, expectedErr: fmt.Errorf("cannot take the variance of 1 values")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, v := range tt.values {
+				assert.NoError(t, tt.acc.Add(v))
+			}
+			v, err := tt.acc.Result()
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.InDelta(t, tt.expected, v, 1e-9)
+		})
+	}
+}
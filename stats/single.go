@@ -0,0 +1,60 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+)
+
+// Abs is a single op that returns the absolute value of a value
+func Abs(v float64) (float64, error) {
+	return math.Abs(v), nil
+}
+
+// Log is a single op that returns the natural logarithm of a value, values that are not positive fail
+func Log(v float64) (float64, error) {
+	if v <= 0 {
+		return 0, fmt.Errorf("cannot take the logarithm of %v", v)
+	}
+	return math.Log(v), nil
+}
+
+// Clamp returns a single op that limits values to [lo, hi]
+func Clamp(lo, hi float64) func(float64) (float64, error) {
+	return func(v float64) (float64, error) {
+		if lo > hi {
+			return 0, fmt.Errorf("cannot clamp values to [%v, %v]", lo, hi)
+		}
+		return math.Max(lo, math.Min(hi, v)), nil
+	}
+}
+
+// Round returns a single op that rounds values to the given number of decimals, halves away from zero
+func Round(decimals int) func(float64) (float64, error) {
+	pow := math.Pow(10, float64(decimals))
+	return func(v float64) (float64, error) {
+		return math.Round(v*pow) / pow, nil
+	}
+}
+
+// Scale returns a single op that multiplies values by the given factor
+func Scale(factor float64) func(float64) (float64, error) {
+	return func(v float64) (float64, error) {
+		return v * factor, nil
+	}
+}
+
+// Bin returns a single op that replaces values with the index of the bin they fall into, out of n bins of equal width
+// that span [lo, hi], see Histogram. Values outside of [lo, hi] fail
+func Bin(lo, hi float64, n int) func(float64) (float64, error) {
+	bin, err := binOf(lo, hi, n)
+	return func(v float64) (float64, error) {
+		if err != nil {
+			return 0, err
+		}
+		i, ok := bin(v)
+		if !ok {
+			return 0, fmt.Errorf("value %v is not within [%v, %v]", v, lo, hi)
+		}
+		return float64(i), nil
+	}
+}
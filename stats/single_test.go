@@ -0,0 +1,47 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSingleOps(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		op          func(float64) (float64, error)
+		value       float64
+		expected    float64
+		expectedErr error
+	}{
+		{name: "test_abs", op: Abs, value: -2.5, expected: 2.5},
+		{name: "test_log", op: Log, value: math.E, expected: 1},
+		{name: "test_log_of_zero", op: Log, value: 0, expectedErr: fmt.Errorf("cannot take the logarithm of 0")},
+		{name: "test_clamp_below", op: Clamp(0, 1), value: -1, expected: 0},
+		{name: "test_clamp_above", op: Clamp(0, 1), value: 2, expected: 1},
+		{name: "test_clamp_within", op: Clamp(0, 1), value: 0.5, expected: 0.5},
+		{name: "test_clamp_of_empty_range", op: Clamp(1, 0), value: 0.5, expectedErr: fmt.Errorf("cannot clamp values to [1, 0]")},
+		{name: "test_round", op: Round(2), value: 1.23456, expected: 1.23},
+		{name: "test_round_half_away_from_zero", op: Round(0), value: -2.5, expected: -3},
+		{name: "test_round_to_tens", op: Round(-1), value: 123, expected: 120},
+		{name: "test_scale", op: Scale(100), value: 0.25, expected: 25},
+		{name: "test_bin", op: Bin(0, 10, 5), value: 4.5, expected: 2},
+		{name: "test_bin_of_upper_bound", op: Bin(0, 10, 5), value: 10, expected: 4},
+		{name: "test_bin_out_of_range", op: Bin(0, 10, 5), value: 11, expectedErr: fmt.Errorf("value 11 is not within [0, 10]")},
+		{name: "test_bin_without_bins", op: Bin(0, 10, 0), value: 1, expectedErr: fmt.Errorf("cannot bin values into 0 bins")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := tt.op(tt.value)
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.InDelta(t, tt.expected, v, 1e-9)
+		})
+	}
+}
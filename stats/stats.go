@@ -0,0 +1,232 @@
+// stats package is responsible for holding ready-made statistics ops that can be used by pipes, e.g with
+// pipe.NewAggregateOpPipe, pipe.NewSingleOpsPipe and pipe.NewAccumulatorPipe
+package stats
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Sum is an aggregate op that sums values with compensated (Kahan) summation, so that rounding errors do not build up
+// over long columns
+func Sum(values []float64) (float64, error) {
+	k := kahan{}
+	for _, v := range values {
+		k.add(v)
+	}
+	return k.result(), nil
+}
+
+// Product is an aggregate op that multiplies values, the product of no values is 1
+func Product(values []float64) (float64, error) {
+	product := 1.0
+	for _, v := range values {
+		product *= v
+	}
+	return product, nil
+}
+
+// Mean is an aggregate op that averages values with Welford's online algorithm
+func Mean(values []float64) (float64, error) {
+	if len(values) == 0 {
+		return 0, fmt.Errorf("cannot average no values")
+	}
+	w := welford{}
+	for _, v := range values {
+		w.add(v)
+	}
+	return w.mean, nil
+}
+
+// Variance is an aggregate op that returns the population variance of values with Welford's online algorithm
+func Variance(values []float64) (float64, error) {
+	if len(values) == 0 {
+		return 0, fmt.Errorf("cannot take the variance of no values")
+	}
+	w := welford{}
+	for _, v := range values {
+		w.add(v)
+	}
+	return w.variance(false), nil
+}
+
+// SampleVariance is an aggregate op that returns the sample variance of values, i.e with Bessel's correction
+func SampleVariance(values []float64) (float64, error) {
+	if len(values) < 2 {
+		return 0, fmt.Errorf("cannot take the sample variance of less than 2 values")
+	}
+	w := welford{}
+	for _, v := range values {
+		w.add(v)
+	}
+	return w.variance(true), nil
+}
+
+// StdDev is an aggregate op that returns the population standard deviation of values
+func StdDev(values []float64) (float64, error) {
+	v, err := Variance(values)
+	if err != nil {
+		return 0, fmt.Errorf("cannot take the standard deviation of no values")
+	}
+	return math.Sqrt(v), nil
+}
+
+// SampleStdDev is an aggregate op that returns the sample standard deviation of values
+func SampleStdDev(values []float64) (float64, error) {
+	v, err := SampleVariance(values)
+	if err != nil {
+		return 0, fmt.Errorf("cannot take the sample standard deviation of less than 2 values")
+	}
+	return math.Sqrt(v), nil
+}
+
+// Median is an aggregate op that returns the median of values, the mean of the two middle values when their number
+// is even
+func Median(values []float64) (float64, error) {
+	if len(values) == 0 {
+		return 0, fmt.Errorf("cannot take the median of no values")
+	}
+	return quantile(sorted(values), 0.5), nil
+}
+
+// Quantile returns an aggregate op that returns the q quantile of values, q within [0, 1], interpolating linearly
+// between the two closest values, e.g Quantile(0.9) is the 90th percentile
+func Quantile(q float64) func([]float64) (float64, error) {
+	return func(values []float64) (float64, error) {
+		if q < 0 || q > 1 || math.IsNaN(q) {
+			return 0, fmt.Errorf("quantile %v is not within [0, 1]", q)
+		}
+		if len(values) == 0 {
+			return 0, fmt.Errorf("cannot take the %v quantile of no values", q)
+		}
+		return quantile(sorted(values), q), nil
+	}
+}
+
+// Min is an aggregate op that returns the smallest of values
+func Min(values []float64) (float64, error) {
+	if len(values) == 0 {
+		return 0, fmt.Errorf("cannot take the minimum of no values")
+	}
+	min := math.Inf(1)
+	for _, v := range values {
+		min = math.Min(min, v)
+	}
+	return min, nil
+}
+
+// Max is an aggregate op that returns the largest of values
+func Max(values []float64) (float64, error) {
+	if len(values) == 0 {
+		return 0, fmt.Errorf("cannot take the maximum of no values")
+	}
+	max := math.Inf(-1)
+	for _, v := range values {
+		max = math.Max(max, v)
+	}
+	return max, nil
+}
+
+// Count is an aggregate op that counts values
+func Count(values []float64) (float64, error) {
+	return float64(len(values)), nil
+}
+
+// DistinctCount is an aggregate op that counts the distinct values
+func DistinctCount(values []float64) (float64, error) {
+	distinct := map[float64]bool{}
+	for _, v := range values {
+		distinct[v] = true
+	}
+	return float64(len(distinct)), nil
+}
+
+// Mode is an aggregate op that returns the most frequent of values, the smallest one when several values are the most
+// frequent
+func Mode(values []float64) (float64, error) {
+	if len(values) == 0 {
+		return 0, fmt.Errorf("cannot take the mode of no values")
+	}
+	counts := map[float64]int{}
+	for _, v := range values {
+		counts[v]++
+	}
+	mode, most := 0.0, 0
+	for v, n := range counts {
+		if n > most || (n == most && v < mode) {
+			mode, most = v, n
+		}
+	}
+	return mode, nil
+}
+
+// Histogram counts values into n bins of equal width that span [lo, hi], values outside of it are not counted
+func Histogram(values []float64, lo, hi float64, n int) ([]int, error) {
+	bin, err := binOf(lo, hi, n)
+	if err != nil {
+		return nil, err
+	}
+	counts := make([]int, n)
+	for _, v := range values {
+		if i, ok := bin(v); ok {
+			counts[i]++
+		}
+	}
+	return counts, nil
+}
+
+// BinCount returns an aggregate op that counts the values that fall into bin i of the n bins of equal width that span
+// [lo, hi], e.g to output a histogram with a multi aggregate pipe, one aggregate per bin
+func BinCount(lo, hi float64, n, i int) func([]float64) (float64, error) {
+	return func(values []float64) (float64, error) {
+		if i < 0 || i >= n {
+			return 0, fmt.Errorf("bin %d is not one of the %d bins", i, n)
+		}
+		counts, err := Histogram(values, lo, hi, n)
+		if err != nil {
+			return 0, err
+		}
+		return float64(counts[i]), nil
+	}
+}
+
+// binOf returns a function that tells which of the n bins of equal width that span [lo, hi] a value falls into, hi
+// itself falls into the last bin
+func binOf(lo, hi float64, n int) (func(float64) (int, bool), error) {
+	if n < 1 {
+		return nil, fmt.Errorf("cannot bin values into %d bins", n)
+	}
+	if !(lo < hi) {
+		return nil, fmt.Errorf("cannot bin values into [%v, %v]", lo, hi)
+	}
+	width := (hi - lo) / float64(n)
+	return func(v float64) (int, bool) {
+		if v < lo || v > hi || math.IsNaN(v) {
+			return 0, false
+		}
+		i := int((v - lo) / width)
+		if i >= n {
+			i = n - 1
+		}
+		return i, true
+	}, nil
+}
+
+// sorted returns a sorted copy of values
+func sorted(values []float64) []float64 {
+	s := append([]float64{}, values...)
+	sort.Float64s(s)
+	return s
+}
+
+// quantile returns the q quantile of the given sorted values
+func quantile(s []float64, q float64) float64 {
+	pos := q * float64(len(s)-1)
+	i := int(math.Floor(pos))
+	if i >= len(s)-1 {
+		return s[len(s)-1]
+	}
+	frac := pos - float64(i)
+	return s[i] + frac*(s[i+1]-s[i])
+}
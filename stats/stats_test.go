@@ -0,0 +1,169 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregateOps(t *testing.T) {
+	t.Parallel()
+	values := []float64{4, 1, 3, 1, 6}
+	tests := []struct {
+		name        string
+		op          func([]float64) (float64, error)
+		values      []float64
+		expected    float64
+		expectedErr error
+	}{
+		{name: "test_sum", op: Sum, values: values, expected: 15},
+		{name: "test_sum_of_no_values", op: Sum, values: nil, expected: 0},
+		{name: "test_product", op: Product, values: values, expected: 72},
+		{name: "test_product_of_no_values", op: Product, values: nil, expected: 1},
+		{name: "test_mean", op: Mean, values: values, expected: 3},
+		{name: "test_variance", op: Variance, values: values, expected: 3.6},
+		{name: "test_sample_variance", op: SampleVariance, values: values, expected: 4.5},
+		{name: "test_std_dev", op: StdDev, values: []float64{2, 4, 4, 4, 5, 5, 7, 9}, expected: 2},
+		{name: "test_sample_std_dev", op: SampleStdDev, values: []float64{1, 3}, expected: math.Sqrt2},
+		{name: "test_median_of_odd_count", op: Median, values: values, expected: 3},
+		{name: "test_median_of_even_count", op: Median, values: []float64{4, 1, 3, 2}, expected: 2.5},
+		{name: "test_quantile", op: Quantile(0.25), values: []float64{1, 2, 3, 4, 5}, expected: 2},
+		{name: "test_quantile_interpolates", op: Quantile(0.9), values: []float64{1, 2, 3, 4, 5}, expected: 4.6},
+		{name: "test_max_quantile", op: Quantile(1), values: values, expected: 6},
+		{name: "test_min", op: Min, values: values, expected: 1},
+		{name: "test_max", op: Max, values: values, expected: 6},
+		{name: "test_count", op: Count, values: values, expected: 5},
+		{name: "test_distinct_count", op: DistinctCount, values: values, expected: 4},
+		{name: "test_mode", op: Mode, values: values, expected: 1},
+		{name: "test_mode_of_ties_is_smallest", op: Mode, values: []float64{3, 2, 3, 2}, expected: 2},
+		{name: "test_bin_count", op: BinCount(0, 6, 3, 0), values: values, expected: 2},
+		{name: "test_mean_of_no_values", op: Mean, expectedErr: fmt.Errorf("cannot average no values")},
+		{name: "test_variance_of_no_values", op: Variance, expectedErr: fmt.Errorf("cannot take the variance of no values")},
+		{name: "test_sample_variance_of_a_value", op: SampleVariance, values: []float64{1}, expectedErr: fmt.Errorf("cannot take the sample variance of less than 2 values")},
+		{name: "test_std_dev_of_no_values", op: StdDev, expectedErr: fmt.Errorf("cannot take the standard deviation of no values")},
+		{name: "test_median_of_no_values", op: Median, expectedErr: fmt.Errorf("cannot take the median of no values")},
+		{name: "test_quantile_out_of_range", op: Quantile(2), values: values, expectedErr: fmt.Errorf("quantile 2 is not within [0, 1]")},
+		{name: "test_min_of_no_values", op: Min, expectedErr: fmt.Errorf("cannot take the minimum of no values")},
+		{name: "test_mode_of_no_values", op: Mode, expectedErr: fmt.Errorf("cannot take the mode of no values")},
+		{name: "test_bin_count_of_missing_bin", op: BinCount(0, 6, 3, 3), values: values, expectedErr: fmt.Errorf("bin 3 is not one of the 3 bins")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := tt.op(tt.values)
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.InDelta(t, tt.expected, v, 1e-9)
+		})
+	}
+}
+
+func TestSum_IsCompensated(t *testing.T) {
+	values := []float64{1e16, 1, -1e16}
+	naive := 0.0
+	for _, v := range values {
+		naive += v
+	}
+	assert.Equal(t, 0.0, naive)
+	sum, err := Sum(values)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, sum)
+}
+
+func TestSumAndMean_NonFinite(t *testing.T) {
+	t.Parallel()
+	inf := math.Inf(1)
+	tests := []struct {
+		name     string
+		values   []float64
+		expected float64
+	}{
+		{name: "test_positive_inf", values: []float64{inf}, expected: inf},
+		{name: "test_negative_inf", values: []float64{-inf}, expected: -inf},
+		{name: "test_inf_among_finite_values", values: []float64{1, inf, 2}, expected: inf},
+		{name: "test_inf_first", values: []float64{inf, 1}, expected: inf},
+		{name: "test_negative_inf_among_finite_values", values: []float64{1e16, -inf, 1}, expected: -inf},
+		{name: "test_infs_of_both_signs", values: []float64{inf, 1, -inf}, expected: math.NaN()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mean := &MeanAccumulator{}
+			sum := &SumAccumulator{}
+			for _, v := range tt.values {
+				assert.NoError(t, mean.Add(v))
+				assert.NoError(t, sum.Add(v))
+			}
+			for _, op := range []func() (float64, error){
+				func() (float64, error) { return Sum(tt.values) },
+				func() (float64, error) { return Mean(tt.values) },
+				sum.Result,
+				mean.Result,
+			} {
+				v, err := op()
+				assert.NoError(t, err)
+				if math.IsNaN(tt.expected) {
+					assert.True(t, math.IsNaN(v))
+				} else {
+					assert.Equal(t, tt.expected, v)
+				}
+			}
+		})
+	}
+}
+
+func TestVariance_IsStable(t *testing.T) {
+	// a large offset makes the naive sum of squares lose all the precision of the variance
+	values := []float64{1e9 + 4, 1e9 + 7, 1e9 + 13, 1e9 + 16}
+	v, err := SampleVariance(values)
+	assert.NoError(t, err)
+	assert.Equal(t, 30.0, v)
+}
+
+func TestHistogram(t *testing.T) {
+	tests := []struct {
+		name        string
+		values      []float64
+		lo, hi      float64
+		n           int
+		expected    []int
+		expectedErr error
+	}{
+		{
+			name:     "test_counts_values_per_bin",
+			values:   []float64{0, 0.5, 1, 2.5, 3, 4},
+			lo:       0,
+			hi:       3,
+			n:        3,
+			expected: []int{2, 1, 2},
+		},
+		{
+			name:        "test_returns_err_on_no_bins",
+			lo:          0,
+			hi:          3,
+			n:           0,
+			expectedErr: fmt.Errorf("cannot bin values into 0 bins"),
+		},
+		{
+			name:        "test_returns_err_on_empty_range",
+			lo:          3,
+			hi:          3,
+			n:           1,
+			expectedErr: fmt.Errorf("cannot bin values into [3, 3]"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counts, err := Histogram(tt.values, tt.lo, tt.hi, tt.n)
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, counts)
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package structure
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/flaviuvadan/pipe-flow/column"
+	"github.com/flaviuvadan/pipe-flow/pipe"
+)
+
+// checkCycle checks that following the upstream pipes of the given pipe never leads back to a pipe that was already
+// visited on the way, which would make the pipes wait on each other forever
+func checkCycle(p *pipe.Pipe) error {
+	var path []*pipe.Pipe
+	onPath := map[*pipe.Pipe]bool{}
+	done := map[*pipe.Pipe]bool{}
+	var visit func(p *pipe.Pipe) error
+	visit = func(p *pipe.Pipe) error {
+		if onPath[p] {
+			names := []string{p.Description}
+			for i := len(path) - 1; i >= 0 && path[i] != p; i-- {
+				names = append(names, path[i].Description)
+			}
+			names = append(names, p.Description)
+			return fmt.Errorf("pipe (%s) is part of a cycle: %s", p.Description, strings.Join(names, " -> "))
+		}
+		if done[p] {
+			return nil
+		}
+		onPath[p] = true
+		path = append(path, p)
+		for _, up := range p.GetUpstream() {
+			if err := visit(up); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		onPath[p] = false
+		done[p] = true
+		return nil
+	}
+	return visit(p)
+}
+
+// waves sorts the pipes of the structure topologically into waves: the first wave is made of the pipes of the sources
+// and every following wave of the pipes whose upstream pipes are all part of earlier waves. Pipes of the same wave are
+// independent of each other and can flow in parallel
+func (s *Structure) waves() ([][]*pipe.Pipe, error) {
+	level := map[*pipe.Pipe]int{}
+	var first []*pipe.Pipe
+	for _, src := range s.sources() {
+		first = append(first, src.OrderedPipes()...)
+	}
+	for _, p := range first {
+		if len(p.GetUpstream()) != 0 {
+			return nil, fmt.Errorf("pipe (%s) is bound to a source and cannot have upstream pipes", p.Description)
+		}
+		level[p] = 0
+	}
+	registered := map[*pipe.Pipe]bool{}
+	for _, p := range s.Pipes {
+		registered[p] = true
+	}
+	visiting := map[*pipe.Pipe]bool{}
+	var levelOf func(p *pipe.Pipe) (int, error)
+	levelOf = func(p *pipe.Pipe) (int, error) {
+		if l, ok := level[p]; ok {
+			return l, nil
+		}
+		if visiting[p] {
+			return 0, fmt.Errorf("pipe (%s) is part of a cycle", p.Description)
+		}
+		if len(p.GetUpstream()) == 0 {
+			return 0, fmt.Errorf("pipe (%s) has neither a source column nor upstream pipes", p.Description)
+		}
+		visiting[p] = true
+		l := 0
+		for _, up := range p.GetUpstream() {
+			if _, ok := level[up]; !ok && !registered[up] {
+				return 0, fmt.Errorf("pipe (%s) depends on pipe (%s) that is not part of the structure", p.Description, up.Description)
+			}
+			ul, err := levelOf(up)
+			if err != nil {
+				return 0, err
+			}
+			if ul+1 > l {
+				l = ul + 1
+			}
+		}
+		visiting[p] = false
+		level[p] = l
+		return l, nil
+	}
+
+	waves := [][]*pipe.Pipe{first}
+	for _, p := range s.Pipes {
+		l, err := levelOf(p)
+		if err != nil {
+			return nil, err
+		}
+		for len(waves) <= l {
+			waves = append(waves, nil)
+		}
+	}
+	for _, p := range s.Pipes {
+		waves[level[p]] = append(waves[level[p]], p)
+	}
+	return waves, nil
+}
+
+// upstreamInput merges the given outputs of the upstream pipes of p into the input of p
+func upstreamInput(p *pipe.Pipe, outs map[*pipe.Pipe]map[string]*column.Column) (map[string]*column.Column, error) {
+	in := map[string]*column.Column{}
+	from := map[string]*pipe.Pipe{}
+	for _, up := range p.GetUpstream() {
+		for k, c := range outs[up] {
+			if o, ok := from[k]; ok {
+				return nil, fmt.Errorf("pipe (%s) receives column (%s) from both pipes (%s) and (%s)", p.Description, k, o.Description, up.Description)
+			}
+			from[k] = up
+			in[k] = c
+		}
+	}
+	return in, nil
+}
+
+// outputNames returns the names of the output columns of every pipe of the structure, pipes bound to the sources are
+// named after their columns and chained pipes after the output of their upstream pipes
+func (s *Structure) outputNames() map[*pipe.Pipe][]string {
+	in := map[*pipe.Pipe][]string{}
+	for _, src := range s.sources() {
+		for _, b := range src.Bindings() {
+			in[b.Pipe] = append(in[b.Pipe], b.Columns...)
+		}
+	}
+	names := map[*pipe.Pipe][]string{}
+	// waves already succeeded once the names are needed
+	waves, _ := s.waves()
+	for i, wave := range waves {
+		for _, p := range wave {
+			if i > 0 {
+				for _, up := range p.GetUpstream() {
+					in[p] = append(in[p], names[up]...)
+				}
+			}
+			names[p] = p.GetOutputNames(in[p])
+		}
+	}
+	return names
+}
+
+// checkOutputNames checks that no pipe outputs several columns of the same name, e.g a chained pipe with an output name
+// whose upstream pipes output several columns
+func (s *Structure) checkOutputNames() error {
+	names := s.outputNames()
+	waves, _ := s.waves()
+	for _, wave := range waves {
+		for _, p := range wave {
+			seen := map[string]bool{}
+			for _, name := range names[p] {
+				if seen[name] {
+					return fmt.Errorf("pipe (%s) outputs column (%s) more than once, output names need a single input column", p.Description, name)
+				}
+				seen[name] = true
+			}
+		}
+	}
+	return nil
+}
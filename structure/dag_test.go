@@ -0,0 +1,180 @@
+package structure
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flaviuvadan/pipe-flow/pipe"
+	"github.com/flaviuvadan/pipe-flow/sink"
+	"github.com/flaviuvadan/pipe-flow/source"
+)
+
+// addPipe returns a test pipe that adds n to values and names its output col
+func addPipe(ds, col string, n float64) *pipe.Pipe {
+	p := pipe.NewSingleOpsPipe(ds, []func(float64) (float64, error){
+		func(v float64) (float64, error) {
+			return v + n, nil
+		},
+	})
+	p.SetOutputName(col)
+	return p
+}
+
+func TestStructure_Register_Cycles(t *testing.T) {
+	a := addPipe("a", "a", 1)
+	b := addPipe("b", "b", 1)
+	c := addPipe("c", "c", 1)
+	b.SetUpstream(a)
+	c.SetUpstream(b)
+	a.SetUpstream(c)
+	self := addPipe("self", "self", 1)
+	self.SetUpstream(self)
+	ok := addPipe("ok", "ok", 1)
+	ok.SetUpstream(addPipe("up", "up", 1))
+	tests := []struct {
+		name        string
+		pipe        *pipe.Pipe
+		expectedErr error
+	}{
+		{
+			name: "test_registers_pipe_without_cycle",
+			pipe: ok,
+		},
+		{
+			name:        "test_detects_cycle",
+			pipe:        a,
+			expectedErr: fmt.Errorf("pipe (a) is part of a cycle: a -> b -> c -> a"),
+		},
+		{
+			name:        "test_detects_pipe_that_is_its_own_upstream",
+			pipe:        self,
+			expectedErr: fmt.Errorf("pipe (self) is part of a cycle: self -> self"),
+		},
+		{
+			name:        "test_returns_err_on_pipe_without_upstream",
+			pipe:        addPipe("alone", "alone", 1),
+			expectedErr: fmt.Errorf("pipe (alone) has no upstream pipes, bind it to a source column instead"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewStructure("test")
+			err := s.Register(tt.pipe)
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				assert.Empty(t, s.Pipes)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, []*pipe.Pipe{tt.pipe}, s.Pipes)
+		})
+	}
+}
+
+func TestStructure_waves(t *testing.T) {
+	a := addPipe("a", "a", 1)
+	b := addPipe("b", "b", 1)
+	ab := addPipe("ab", "ab", 1)
+	ab.SetUpstream(a, b)
+	aa := addPipe("aa", "aa", 1)
+	aa.SetUpstream(a)
+	abaa := addPipe("abaa", "abaa", 1)
+	abaa.SetUpstream(ab, aa)
+	orphan := addPipe("orphan", "orphan", 1)
+	orphan.SetUpstream(addPipe("unknown", "unknown", 1))
+
+	s := NewStructure("test")
+	s.Sources = []*source.Source{{Pipes: map[string]*pipe.Pipe{"a": a, "b": b}}}
+	s.Pipes = []*pipe.Pipe{abaa, ab, aa}
+	waves, err := s.waves()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]*pipe.Pipe{{a, b}, {ab, aa}, {abaa}}, waves)
+
+	s.Pipes = append(s.Pipes, orphan)
+	_, err = s.waves()
+	assert.EqualError(t, err, "pipe (orphan) depends on pipe (unknown) that is not part of the structure")
+}
+
+func TestStructure_Flow_Chained(t *testing.T) {
+	tests := []struct {
+		name        string
+		chunkSize   int
+		failing     bool
+		expected    string
+		expectedErr error
+	}{
+		{
+			name:     "test_flows_chained_pipes",
+			expected: "a,a_plus1,a_plus11\n1.000,2.000,12.000\n2.000,3.000,13.000\n3.000,4.000,14.000\n",
+		},
+		{
+			name:      "test_streams_chained_pipes",
+			chunkSize: 2,
+			expected:  "a,a_plus1,a_plus11\n1.000,2.000,12.000\n2.000,3.000,13.000\n3.000,4.000,14.000\n",
+		},
+		{
+			name:        "test_does_not_flow_downstream_of_failed_pipe",
+			failing:     true,
+			expectedErr: fmt.Errorf("1 pipe/s failed to flow: pipe (a_plus1) failed to flow, err: failed to apply op to val 1 on row 0 with op msg: failed"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := addPipe("a", "a", 0)
+			plusOne := addPipe("a_plus1", "a_plus1", 1)
+			if tt.failing {
+				plusOne = pipe.NewSingleOpsPipe("a_plus1", []func(float64) (float64, error){
+					func(v float64) (float64, error) {
+						return 0, fmt.Errorf("failed")
+					},
+				})
+			}
+			plusOne.SetUpstream(a)
+			plusEleven := addPipe("a_plus11", "a_plus11", 10)
+			plusEleven.SetUpstream(plusOne)
+			src, err := source.NewSource("test", "test_stream.csv", map[string]*pipe.Pipe{"a": a}, source.WithChunkSize(tt.chunkSize))
+			assert.NoError(t, err)
+			fn := tt.name + ".csv"
+			snk, _ := sink.NewSink(fn, []*pipe.Pipe{a, plusOne, plusEleven})
+			s := NewStructure("test")
+			assert.NoError(t, s.Register(src))
+			assert.NoError(t, s.Register(snk))
+			assert.NoError(t, s.Register(plusEleven))
+			assert.NoError(t, s.Register(plusOne))
+			_, err = s.Flow()
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				assert.Nil(t, plusEleven.GetTypedInput())
+				return
+			}
+			assert.NoError(t, err)
+			content, err := ioutil.ReadFile(fn)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, string(content))
+			if err := os.Remove(fn); err != nil {
+				panic(fmt.Errorf("could not remove %v for tests teardown", fn))
+			}
+		})
+	}
+}
+
+func TestStructure_Flow_ChainedOutputNameOfSeveralColumns(t *testing.T) {
+	a := addPipe("a", "a", 0)
+	b := addPipe("b", "b", 0)
+	total := addPipe("total", "total", 0)
+	total.SetUpstream(a, b)
+	src, err := source.NewSource("test", "test_stream.csv", map[string]*pipe.Pipe{"a": a, "b": b})
+	assert.NoError(t, err)
+	snk, _ := sink.NewSink("test_chained_output_name.csv", []*pipe.Pipe{total})
+	s := NewStructure("test")
+	assert.NoError(t, s.Register(src))
+	assert.NoError(t, s.Register(snk))
+	assert.NoError(t, s.Register(total))
+	_, err = s.Flow()
+	assert.EqualError(t, err, "pipe (total) outputs column (total) more than once, output names need a single input column")
+	assert.Nil(t, a.GetTypedOutput())
+}
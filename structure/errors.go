@@ -0,0 +1,45 @@
+package structure
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FlowError aggregates all the errors that pipes returned while flowing through a structure
+type FlowError struct {
+	Errors []error // errors of the pipes that failed, each one names the failing pipe
+}
+
+// Error returns all the aggregated errors as a single message
+func (e *FlowError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d pipe/s failed to flow: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// Is reports whether any of the aggregated errors matches the target, e.g context.Canceled
+func (e *FlowError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// DumpError aggregates all the errors that sinks returned while dumping the results of a structure
+type DumpError struct {
+	Errors []error // errors of the sinks that failed, each one names the failing sink
+}
+
+// Error returns all the aggregated errors as a single message
+func (e *DumpError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d sink/s failed to dump: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
@@ -0,0 +1,68 @@
+package structure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlowError_Error(t *testing.T) {
+	tests := []struct {
+		name     string
+		errs     []error
+		expected string
+	}{
+		{
+			name:     "test_formats_single_error",
+			errs:     []error{fmt.Errorf("pipe (a) failed")},
+			expected: "1 pipe/s failed to flow: pipe (a) failed",
+		},
+		{
+			name:     "test_formats_multiple_errors",
+			errs:     []error{fmt.Errorf("pipe (a) failed"), fmt.Errorf("pipe (b) failed")},
+			expected: "2 pipe/s failed to flow: pipe (a) failed; pipe (b) failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &FlowError{Errors: tt.errs}
+			assert.EqualError(t, e, tt.expected)
+		})
+	}
+}
+
+func TestFlowError_Is(t *testing.T) {
+	tests := []struct {
+		name     string
+		errs     []error
+		target   error
+		expected bool
+	}{
+		{
+			name:     "test_matches_wrapped_error",
+			errs:     []error{fmt.Errorf("pipe (a) failed"), fmt.Errorf("pipe (b) stopped, err: %w", context.Canceled)},
+			target:   context.Canceled,
+			expected: true,
+		},
+		{
+			name:     "test_does_not_match_missing_error",
+			errs:     []error{fmt.Errorf("pipe (a) failed")},
+			target:   context.DeadlineExceeded,
+			expected: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &FlowError{Errors: tt.errs}
+			assert.Equal(t, tt.expected, errors.Is(e, tt.target))
+		})
+	}
+}
+
+func TestDumpError_Error(t *testing.T) {
+	e := &DumpError{Errors: []error{fmt.Errorf("sink (a) failed"), fmt.Errorf("sink (b) failed")}}
+	assert.EqualError(t, e, "2 sink/s failed to dump: sink (a) failed; sink (b) failed")
+}
@@ -0,0 +1,160 @@
+package structure
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/flaviuvadan/pipe-flow/pipe"
+)
+
+// RunReport tells how a flow of the structure went, it can be serialized to JSON, e.g to be stored next to the files
+// the sinks dumped. Durations are serialized in nanoseconds and errors as their message
+type RunReport struct {
+	Description string         `json:"description"` // Description of the structure
+	Start       time.Time      `json:"start"`       // when the flow started
+	End         time.Time      `json:"end"`         // when the flow ended, i.e once every sink dumped
+	Duration    time.Duration  `json:"duration"`    // how long the whole flow took
+	Sources     []SourceReport `json:"sources"`     // reports of the sources, in the order they were registered
+	Pipes       []PipeReport   `json:"pipes"`       // reports of the pipes, those of the sources first and then the registered ones
+	Sinks       []SinkReport   `json:"sinks"`       // reports of the sinks, in the order they were registered
+}
+
+// SourceReport tells what a source of the structure read
+type SourceReport struct {
+	Name      string         `json:"name"`       // name of the source, i.e its Description
+	File      string         `json:"file"`       // CSV file the source read
+	Rows      int            `json:"rows"`       // number of data rows the source read, the header excluded
+	BytesRead int64          `json:"bytes_read"` // number of bytes the source read from its file
+	Nulls     map[string]int `json:"nulls"`      // number of null cells the source read in every CSV column
+}
+
+// PipeReport tells how a pipe of the structure flowed
+type PipeReport struct {
+	Name     string        `json:"name"`     // name of the pipe, i.e its Description
+	Duration time.Duration `json:"duration"` // how long the pipe flowed, or streamed
+	RowsIn   int           `json:"rows_in"`  // number of rows that entered the pipe
+	RowsOut  int           `json:"rows_out"` // number of rows that left the pipe
+	Dropped  int           `json:"dropped"`  // number of rows the filters of the pipe dropped
+	Errors   int           `json:"errors"`   // number of failed rows the error policy of the pipe handled
+	Retries  int           `json:"retries"`  // number of times the ops of the pipe were retried
+	Attempts int           `json:"attempts"` // number of times the pipe tried to flow, retries of the whole flow included
+	Nulls    int           `json:"nulls"`    // number of null values the pipe output
+	Err      error         `json:"-"`        // why the pipe failed to flow, nil when it succeeded
+}
+
+// SinkReport tells what a sink of the structure dumped
+type SinkReport struct {
+	Name         string   `json:"name"`          // name of the sink, i.e its file
+	Path         string   `json:"path"`          // path of the file the sink wrote
+	Columns      []string `json:"columns"`       // columns the sink wrote
+	Rows         int      `json:"rows"`          // number of records the sink wrote, the header excluded
+	BytesWritten int64    `json:"bytes_written"` // number of bytes the sink wrote to its file
+	Err          error    `json:"-"`             // why the sink failed to dump, nil when it succeeded
+}
+
+// MarshalJSON serializes the report of the pipe along with the message of its error, if any
+func (r PipeReport) MarshalJSON() ([]byte, error) {
+	type report PipeReport
+	return json.Marshal(struct {
+		report
+		Error string `json:"error,omitempty"`
+	}{report(r), errorMessage(r.Err)})
+}
+
+// MarshalJSON serializes the report of the sink along with the message of its error, if any
+func (r SinkReport) MarshalJSON() ([]byte, error) {
+	type report SinkReport
+	return json.Marshal(struct {
+		report
+		Error string `json:"error,omitempty"`
+	}{report(r), errorMessage(r.Err)})
+}
+
+// String returns the duration of the flow, which is what Flow returned before it returned a RunReport, so that code
+// printing the result of Flow keeps printing the same
+func (r *RunReport) String() string {
+	return r.Duration.String()
+}
+
+// errorMessage returns the message of the given error, empty for nil
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// Succeeded returns the reports of the pipes that succeeded
+func (r *RunReport) Succeeded() []PipeReport {
+	var pipes []PipeReport
+	for _, p := range r.Pipes {
+		if p.Err == nil {
+			pipes = append(pipes, p)
+		}
+	}
+	return pipes
+}
+
+// Failed returns the reports of the pipes that failed, a flow that fails as a whole returns its RunReport along with
+// its error, see Structure.OnFailure
+func (r *RunReport) Failed() []PipeReport {
+	var pipes []PipeReport
+	for _, p := range r.Pipes {
+		if p.Err != nil {
+			pipes = append(pipes, p)
+		}
+	}
+	return pipes
+}
+
+// failedReport returns the report of a flow that failed with the given error before the sinks dumped, along with the
+// error. Every sink reports that it did not dump
+func (s *Structure) failedReport(start time.Time, failed map[*pipe.Pipe]error, err error) (*RunReport, error) {
+	errs := make([]error, len(s.sinks()))
+	for i, snk := range s.sinks() {
+		errs[i] = fmt.Errorf("sink (%s) did not dump as the flow failed", snk.Name())
+	}
+	return s.report(start, failed, errs), err
+}
+
+// report returns the report of a flow that started at the given time, failed holds the error of every pipe that failed
+// and errs the dump error of every sink
+func (s *Structure) report(start time.Time, failed map[*pipe.Pipe]error, errs []error) *RunReport {
+	end := time.Now()
+	r := &RunReport{Description: s.Description, Start: start, End: end, Duration: end.Sub(start)}
+	for _, src := range s.sources() {
+		r.Sources = append(r.Sources, SourceReport{
+			Name:      src.Description,
+			File:      src.File(),
+			Rows:      src.Rows(),
+			BytesRead: src.BytesRead(),
+			Nulls:     src.NullCounts(),
+		})
+	}
+	for _, p := range s.pipes() {
+		pr := PipeReport{Name: p.Description, Err: failed[p]}
+		// pipes downstream of a failed pipe did not flow, the pipes that flowed are reported even when they failed
+		if !errors.Is(failed[p], errDidNotFlow) {
+			pr.Duration = p.GetFlowDuration()
+			pr.RowsIn, pr.RowsOut = p.GetRows()
+			pr.Dropped = p.GetDropped()
+			pr.Errors = p.GetErrors()
+			ops, flows := p.GetRetries()
+			pr.Retries, pr.Attempts = ops, flows+1
+			pr.Nulls = p.GetNulls()
+		}
+		r.Pipes = append(r.Pipes, pr)
+	}
+	for i, snk := range s.sinks() {
+		sr := SinkReport{Name: snk.Name(), Err: errs[i]}
+		if errs[i] == nil {
+			sr.Columns, sr.Rows = snk.Written()
+			sr.Path = snk.Path()
+			sr.BytesWritten = snk.BytesWritten()
+		}
+		r.Sinks = append(r.Sinks, sr)
+	}
+	return r
+}
@@ -0,0 +1,108 @@
+package structure
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flaviuvadan/pipe-flow/pipe"
+	"github.com/flaviuvadan/pipe-flow/sink"
+	"github.com/flaviuvadan/pipe-flow/source"
+)
+
+func TestStructure_Flow_RunReport(t *testing.T) {
+	for _, chunkSize := range []int{0, 2} {
+		t.Run(fmt.Sprintf("test_reports_flow_with_chunk_size_%d", chunkSize), func(t *testing.T) {
+			a := pipe.NewFilterPipe("a_filter", func(v float64) (bool, error) {
+				return v != 2, nil
+			})
+			src, err := source.NewSource("test", "test_stream.csv", map[string]*pipe.Pipe{"a": a},
+				source.WithChunkSize(chunkSize), source.WithUnmapped(source.PassThrough))
+			assert.NoError(t, err)
+			fn := fmt.Sprintf("test_report_%d.csv", chunkSize)
+			snk, _ := sink.NewSink(fn, []*pipe.Pipe{a})
+			s := NewStructure("test")
+			_ = s.Register(src)
+			_ = s.Register(snk)
+			report, err := s.Flow()
+			assert.NoError(t, err)
+			content, err := ioutil.ReadFile(fn)
+			assert.NoError(t, err)
+
+			assert.Equal(t, "test", report.Description)
+			assert.Equal(t, report.End.Sub(report.Start), report.Duration)
+			assert.Equal(t, []SourceReport{
+				{Name: "test", File: "test_stream.csv", Rows: 3, BytesRead: 19, Nulls: map[string]int{}},
+			}, report.Sources)
+			assert.Len(t, report.Pipes, 1)
+			pr := report.Pipes[0]
+			assert.Equal(t, "a_filter", pr.Name)
+			assert.Equal(t, a.GetFlowDuration(), pr.Duration)
+			assert.Equal(t, 3, pr.RowsIn)
+			assert.Equal(t, 2, pr.RowsOut)
+			assert.Equal(t, 1, pr.Dropped)
+			assert.Equal(t, 1, pr.Attempts)
+			assert.Equal(t, []SinkReport{
+				sinkReport(fn, []string{"a", "b"}, 2, string(content)),
+			}, report.Sinks)
+			if err := os.Remove(fn); err != nil {
+				panic(fmt.Errorf("could not remove %v for tests teardown", fn))
+			}
+		})
+	}
+}
+
+func TestRunReport_MarshalJSON(t *testing.T) {
+	r := RunReport{
+		Pipes: []PipeReport{
+			{Name: "a", RowsIn: 3, RowsOut: 2},
+			{Name: "b", Err: fmt.Errorf("failed")},
+		},
+		Sinks: []SinkReport{{Name: "results.csv", Path: "/tmp/results.csv", Rows: 2, BytesWritten: 12}},
+	}
+	b, err := json.Marshal(r)
+	assert.NoError(t, err)
+	var decoded map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b, &decoded))
+	pipes := decoded["pipes"].([]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"name": "a", "duration": 0.0, "rows_in": 3.0, "rows_out": 2.0, "dropped": 0.0, "errors": 0.0, "retries": 0.0,
+		"attempts": 0.0, "nulls": 0.0,
+	}, pipes[0])
+	assert.Equal(t, "failed", pipes[1].(map[string]interface{})["error"])
+	assert.Equal(t, map[string]interface{}{
+		"name": "results.csv", "path": "/tmp/results.csv", "columns": nil, "rows": 2.0, "bytes_written": 12.0,
+	}, decoded["sinks"].([]interface{})[0])
+}
+
+func TestStructure_Flow_RunReport_Retries(t *testing.T) {
+	calls := 0
+	a := pipe.NewSingleOpsPipe("a_flaky", []func(float64) (float64, error){
+		func(v float64) (float64, error) {
+			calls++
+			if calls == 1 {
+				return 0, fmt.Errorf("transient")
+			}
+			return v, nil
+		},
+	})
+	a.SetRetryPolicy(pipe.RetryPolicy{MaxAttempts: 2})
+	src, err := source.NewSource("test", "test_stream.csv", map[string]*pipe.Pipe{"a": a})
+	assert.NoError(t, err)
+	fn := "test_report_retries.csv"
+	snk, _ := sink.NewSink(fn, []*pipe.Pipe{a})
+	s := NewStructure("test")
+	_ = s.Register(src)
+	_ = s.Register(snk)
+	report, err := s.Flow()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Pipes[0].Retries)
+	assert.Equal(t, 1, report.Pipes[0].Attempts)
+	if err := os.Remove(fn); err != nil {
+		panic(fmt.Errorf("could not remove %v for tests teardown", fn))
+	}
+}
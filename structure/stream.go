@@ -0,0 +1,187 @@
+package structure
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/flaviuvadan/pipe-flow/column"
+	"github.com/flaviuvadan/pipe-flow/pipe"
+	"github.com/flaviuvadan/pipe-flow/source"
+)
+
+// flowStream flows a streaming source chunk by chunk: single ops pipes flow every chunk as it is read, wave after wave
+// when they are chained, and the sinks write the resulting rows right away, accumulator pipes accumulate every chunk
+// and the sinks write their results once the source is exhausted. The files of all the sinks are removed when the
+// stream fails or the context is done. The errors of the pipes that failed are set on failed
+func (s *Structure) flowStream(ctx context.Context, src *source.Source, failed map[*pipe.Pipe]error) (err error) {
+	defer func() {
+		if cerr := src.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
+	waves, err := s.waves()
+	if err != nil {
+		return err
+	}
+	cols := map[*pipe.Pipe][]string{}
+	for _, b := range src.Bindings() {
+		cols[b.Pipe] = b.Columns
+	}
+	// output names are worked out wave after wave as chained pipes are named after the output of their upstream pipes
+	names := map[*pipe.Pipe][]string{}
+	var pipes []*pipe.Pipe
+	for i, wave := range waves {
+		for _, p := range wave {
+			if err := p.Begin(); err != nil {
+				failed[p] = err
+				return err
+			}
+			in := cols[p]
+			if i > 0 {
+				in = nil
+				for _, up := range p.GetUpstream() {
+					if up.Accumulates() {
+						return fmt.Errorf("pipe (%s) cannot stream the output of accumulator pipe (%s)", p.Description, up.Description)
+					}
+					if up.Windows() && up.Aggregates() {
+						// tumbling and sliding windows are only output once the stream ends
+						return fmt.Errorf("pipe (%s) cannot stream the output of window pipe (%s)", p.Description, up.Description)
+					}
+					in = append(in, names[up]...)
+				}
+			}
+			names[p] = p.GetOutputNames(in)
+			pipes = append(pipes, p)
+		}
+	}
+
+	inSinks := make([]map[*pipe.Pipe]bool, len(s.sinks()))
+	passThrough := make([][]string, len(s.sinks()))
+	defer func() {
+		if err != nil {
+			for _, snk := range s.sinks() {
+				_ = snk.Abort()
+			}
+		}
+	}()
+	for i, snk := range s.sinks() {
+		inSinks[i] = map[*pipe.Pipe]bool{}
+		for _, p := range snk.Pipes {
+			inSinks[i][p] = true
+		}
+		var header []string
+		for _, p := range pipes {
+			if inSinks[i][p] {
+				header = append(header, names[p]...)
+			}
+		}
+		if src.PassesThrough() && passesThrough(src, snk) {
+			passThrough[i] = src.Unmapped()
+			header = append(header, passThrough[i]...)
+		}
+		// without an explicit order sinks follow the order of the source CSV header
+		if err := snk.Begin(header, src.OutputColumns()...); err != nil {
+			return fmt.Errorf("sink (%s) failed to begin streaming results, err: %v", snk.Name(), err)
+		}
+	}
+
+	for {
+		chunk, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		outs, err := s.flowChunk(ctx, waves, cols, chunk, failed)
+		if err != nil {
+			return err
+		}
+		for i, snk := range s.sinks() {
+			data := sinkData(pipes, outs, inSinks[i])
+			for _, c := range passThrough[i] {
+				data[c] = chunk[c]
+			}
+			if err := snk.Write(data); err != nil {
+				return fmt.Errorf("sink (%s) failed to write results, err: %v", snk.Name(), err)
+			}
+		}
+	}
+
+	outs := map[*pipe.Pipe]map[string]*column.Column{}
+	for _, p := range pipes {
+		out, err := p.End()
+		if err != nil {
+			failed[p] = pipeError(p, err)
+			return failed[p]
+		}
+		outs[p] = out
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("structure (%s) stopped before dumping results, err: %w", s.Description, err)
+	}
+	for i, snk := range s.sinks() {
+		if err := snk.End(sinkData(pipes, outs, inSinks[i])); err != nil {
+			return fmt.Errorf("sink (%s) failed to dump results, err: %v", snk.Name(), err)
+		}
+	}
+	return nil
+}
+
+// flowChunk flows a chunk of the source through the pipes wave after wave, pipes of the first wave get the columns of
+// the chunk they are bound to and the pipes of the following waves get the chunk output of their upstream pipes. The
+// errors of the pipes that failed are set on failed
+func (s *Structure) flowChunk(ctx context.Context, waves [][]*pipe.Pipe, cols map[*pipe.Pipe][]string, chunk map[string]*column.Column, failed map[*pipe.Pipe]error) (map[*pipe.Pipe]map[string]*column.Column, error) {
+	outs := map[*pipe.Pipe]map[string]*column.Column{}
+	for i, wave := range waves {
+		ins := make([]map[string]*column.Column, len(wave))
+		for j, p := range wave {
+			if i == 0 {
+				ins[j] = make(map[string]*column.Column, len(cols[p]))
+				for _, c := range cols[p] {
+					ins[j][c] = chunk[c]
+				}
+				continue
+			}
+			in, err := upstreamInput(p, outs)
+			if err != nil {
+				return nil, err
+			}
+			ins[j] = in
+		}
+		waveOuts := make([]map[string]*column.Column, len(wave))
+		errs := s.runEach(wave, func(j int, p *pipe.Pipe) error {
+			out, err := p.FlowChunk(ctx, ins[j])
+			waveOuts[j] = out
+			return err
+		})
+		if err := flowError(errs); err != nil {
+			for j, p := range wave {
+				if errs[j] != nil {
+					failed[p] = errs[j]
+				}
+			}
+			return nil, err
+		}
+		for j, p := range wave {
+			outs[p] = waveOuts[j]
+		}
+	}
+	return outs, nil
+}
+
+// sinkData merges the outputs of the pipes that are connected to a sink
+func sinkData(pipes []*pipe.Pipe, outs map[*pipe.Pipe]map[string]*column.Column, inSink map[*pipe.Pipe]bool) map[string]*column.Column {
+	data := map[string]*column.Column{}
+	for _, p := range pipes {
+		if !inSink[p] {
+			continue
+		}
+		for k, v := range outs[p] {
+			data[k] = v
+		}
+	}
+	return data
+}
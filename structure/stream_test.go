@@ -0,0 +1,111 @@
+package structure
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flaviuvadan/pipe-flow/pipe"
+	"github.com/flaviuvadan/pipe-flow/sink"
+	"github.com/flaviuvadan/pipe-flow/source"
+)
+
+// sumAccumulator is a test accumulator that sums values
+type sumAccumulator struct {
+	sum float64
+}
+
+func (a *sumAccumulator) Add(v float64) error {
+	a.sum += v
+	return nil
+}
+
+func (a *sumAccumulator) Result() (float64, error) {
+	return a.sum, nil
+}
+
+func TestStructure_flowStream(t *testing.T) {
+	tests := []struct {
+		name        string
+		aOp         func(float64) (float64, error)
+		expected    string
+		expectedErr error
+	}{
+		{
+			name: "test_streams_rows_and_accumulates",
+			aOp: func(v float64) (float64, error) {
+				return v + 1, nil
+			},
+			expected: "a,b\n2.000,\n3.000,\n4.000,\n,60.000\n",
+		},
+		{
+			name: "test_removes_sink_file_on_failure",
+			aOp: func(v float64) (float64, error) {
+				if v > 2 {
+					return 0, fmt.Errorf("too large")
+				}
+				return v, nil
+			},
+			expectedErr: fmt.Errorf("1 pipe/s failed to flow: pipe (a_pipe) failed to flow, err: failed to apply op to val 3 on row 2 with op msg: too large"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := pipe.NewSingleOpsPipe("a_pipe", []func(float64) (float64, error){tt.aOp})
+			b := pipe.NewAccumulatorPipe("b_pipe", func() pipe.Accumulator {
+				return &sumAccumulator{}
+			})
+			src, err := source.NewSource("test", "test_stream.csv", map[string]*pipe.Pipe{"a": a, "b": b}, source.WithChunkSize(2))
+			assert.NoError(t, err)
+			fn := tt.name + ".csv"
+			snk, _ := sink.NewSink(fn, []*pipe.Pipe{b, a})
+			s := NewStructure(tt.name)
+			_ = s.Register(src)
+			_ = s.Register(snk)
+			res, err := s.FlowContext(context.Background())
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				assert.Len(t, res.Failed(), 1)
+				assert.Equal(t, "a_pipe", res.Failed()[0].Name)
+				assert.EqualError(t, res.Sinks[0].Err, fmt.Sprintf("sink (%s) did not dump as the flow failed", fn))
+				_, statErr := os.Stat(fn)
+				assert.True(t, os.IsNotExist(statErr))
+				return
+			}
+			assert.NoError(t, err)
+			content, err := ioutil.ReadFile(fn)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, string(content))
+			if err := os.Remove(fn); err != nil {
+				panic(fmt.Errorf("could not remove %v for tests teardown", fn))
+			}
+		})
+	}
+}
+
+func TestStructure_flowStream_WidensInferredInts(t *testing.T) {
+	for _, chunkSize := range []int{0, 2} {
+		t.Run(fmt.Sprintf("test_widens_ints_with_chunk_size_%d", chunkSize), func(t *testing.T) {
+			src, err := source.NewSource("test", "test_widen.csv", map[string]*pipe.Pipe{"a": addPipe("a", "a", 1)},
+				source.WithChunkSize(chunkSize))
+			assert.NoError(t, err)
+			fn := fmt.Sprintf("test_widen_%d.csv", chunkSize)
+			snk, _ := sink.NewSink(fn, src.OrderedPipes())
+			s := NewStructure("test")
+			_ = s.Register(src)
+			_ = s.Register(snk)
+			_, err = s.Flow()
+			assert.NoError(t, err)
+			content, err := ioutil.ReadFile(fn)
+			assert.NoError(t, err)
+			assert.Equal(t, "a\n2.000\n3.000\n3.500\n", string(content))
+			if err := os.Remove(fn); err != nil {
+				panic(fmt.Errorf("could not remove %v for tests teardown", fn))
+			}
+		})
+	}
+}
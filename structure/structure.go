@@ -3,64 +3,407 @@
 package structure
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"sync"
 	"time"
 
+	"github.com/flaviuvadan/pipe-flow/column"
+	"github.com/flaviuvadan/pipe-flow/pipe"
+	"github.com/flaviuvadan/pipe-flow/progress"
 	"github.com/flaviuvadan/pipe-flow/sink"
 	"github.com/flaviuvadan/pipe-flow/source"
 )
 
+// FailureMode tells what a flow of the structure does when pipes fail
+type FailureMode int
+
+const (
+	// FailAll stops the flow as soon as a wave of pipes fails and no sink dumps, this is the default
+	FailAll FailureMode = iota
+	// FailOmit records the pipes that fail, along with the pipes downstream of them, and lets the other pipes reach the
+	// sinks, which leave the columns of the failed pipes out
+	FailOmit
+	// FailAnnotate is like FailOmit but the sinks write the columns of the failed pipes with their Failed value on every
+	// row, so that the dumped files tell which columns are missing
+	FailAnnotate
+)
+
 // Structure represents the state of the data processing system
 type Structure struct {
-	Description string         // a Description of the structure and what it does e.g the data it processes
-	Inform      bool           // whether to Inform users of the process of the pipelines as they are performing, state informs occur in junctions
-	Source      *source.Source // data Source
-	Sink        *sink.Sink     // data Sink
+	Description string            // a Description of the structure and what it does e.g the data it processes
+	OnFailure   FailureMode       // what the flow does when pipes fail, the RunReport lists the pipes that failed
+	Inform      bool              // whether to Inform users of the process of the pipelines as they are performing, pipes report to Reporter
+	Concurrency int               // maximum number of pipes that flow at the same time, 0 means no limit
+	Reporter    progress.Reporter // receives the progress of pipes when Inform is set, defaults to a bar on stdout
+	ReportEvery int               // number of rows between progress reports of pipes, see pipe.DefaultReportEvery
+	Sources     []*source.Source  // data Sources, named after their Description, in the order they were registered
+	Pipes       []*pipe.Pipe      // pipes that flow the output of upstream pipes rather than columns of the Sources
+	Sinks       []*sink.Sink      // data Sinks, named after their file, in the order they were registered
+
+	// Deprecated: Source is the last registered source, use Sources. A source set here flows along with Sources
+	Source *source.Source
+	// Deprecated: Sink is the last registered sink, use Sinks. A sink set here dumps along with Sinks
+	Sink *sink.Sink
 }
 
 // New returns a new instance of a Structure
 func NewStructure(dsc string) *Structure {
 	return &Structure{
 		Description: dsc,
-		Source:      nil,
-		Sink:        nil,
+		Sources:     nil,
+		Sinks:       nil,
+	}
+}
+
+// sources returns the Sources of the structure along with the deprecated Source when it is not one of them
+func (s *Structure) sources() []*source.Source {
+	for _, src := range s.Sources {
+		if src == s.Source {
+			return s.Sources
+		}
+	}
+	if s.Source == nil {
+		return s.Sources
 	}
+	return append(append([]*source.Source{}, s.Sources...), s.Source)
 }
 
-// Register adds Pipes or junctions to the structure
+// sinks returns the Sinks of the structure along with the deprecated Sink when it is not one of them
+func (s *Structure) sinks() []*sink.Sink {
+	for _, snk := range s.Sinks {
+		if snk == s.Sink {
+			return s.Sinks
+		}
+	}
+	if s.Sink == nil {
+		return s.Sinks
+	}
+	return append(append([]*sink.Sink{}, s.Sinks...), s.Sink)
+}
+
+// Register adds Pipes or junctions to the structure. Sources and sinks are named after their Description and file,
+// respectively, and registering a second one of the same name fails. Registered pipes flow the output of their
+// upstream pipes, see pipe.SetUpstream, and registering a pipe whose upstream pipes lead back to it fails
 func (s *Structure) Register(i interface{}) error {
 	switch v := i.(type) {
 	case *source.Source:
+		if v == nil {
+			return fmt.Errorf("cannot register a nil Source")
+		}
+		for _, src := range s.sources() {
+			if src.Description == v.Description {
+				return fmt.Errorf("a source named (%s) is already registered", v.Description)
+			}
+		}
+		s.Sources = append(s.Sources, v)
 		s.Source = v
 	case *sink.Sink:
+		if v == nil {
+			return fmt.Errorf("cannot register a nil Sink")
+		}
+		for _, snk := range s.sinks() {
+			if snk.Name() == v.Name() {
+				return fmt.Errorf("a sink named (%s) is already registered", v.Name())
+			}
+		}
+		s.Sinks = append(s.Sinks, v)
 		s.Sink = v
+	case *pipe.Pipe:
+		if len(v.GetUpstream()) == 0 {
+			return fmt.Errorf("pipe (%s) has no upstream pipes, bind it to a source column instead", v.Description)
+		}
+		if err := checkCycle(v); err != nil {
+			return err
+		}
+		s.Pipes = append(s.Pipes, v)
 	default:
 		return fmt.Errorf("provided interface cannot be cast to any known type")
 	}
 	return nil
 }
 
-// Flow launches the flow of all the pipelines that are registered with this structure
-func (s *Structure) Flow() (string, error) {
-	if s.Source == nil {
-		return "", fmt.Errorf("cannot flow with nil Source")
+// Flow launches the flow of all the pipelines that are registered with this structure and returns the RunReport of how
+// its sources, pipes and sinks went, see OnFailure for how pipe failures are handled
+func (s *Structure) Flow() (*RunReport, error) {
+	return s.FlowContext(context.Background())
+}
+
+// FlowContext is like Flow but stops all the pipes, and skips the sink dumps, once the given context is done
+func (s *Structure) FlowContext(ctx context.Context) (*RunReport, error) {
+	if len(s.sources()) == 0 {
+		return nil, fmt.Errorf("cannot flow without a Source")
 	}
-	if s.Sink == nil {
-		return "", fmt.Errorf("cannot flow with nil Sink")
+	if len(s.sinks()) == 0 {
+		return nil, fmt.Errorf("cannot flow without a Sink")
+	}
+	for _, src := range s.sources() {
+		if src == nil {
+			return nil, fmt.Errorf("cannot flow with nil Source")
+		}
+	}
+	for _, snk := range s.sinks() {
+		if snk == nil {
+			return nil, fmt.Errorf("cannot flow with nil Sink")
+		}
+	}
+	if err := s.checkSinkColumns(); err != nil {
+		return nil, err
+	}
+	if err := s.checkOutputNames(); err != nil {
+		return nil, err
 	}
 	start := time.Now()
-	// TODO: do this in parallel with an error channel
-	for _, p := range s.Source.Pipes {
-		// a single pipe failure interrupts the whole process, which may not be desirable, linked to TODO above
-		if err := p.Flow(); err != nil {
-			return "", fmt.Errorf("structure failed to make pipe flow, err: %v", err)
+	if s.Inform {
+		ctx = pipe.WithReporter(ctx, s.reporter(), s.ReportEvery)
+	}
+	for _, src := range s.sources() {
+		if !src.Streaming() {
+			continue
+		}
+		if len(s.sources()) > 1 {
+			return nil, fmt.Errorf("streaming source (%s) cannot flow along with other sources", src.Description)
+		}
+		if s.OnFailure != FailAll {
+			return nil, fmt.Errorf("streaming source (%s) cannot flow with partial failures", src.Description)
+		}
+		failed := map[*pipe.Pipe]error{}
+		if err := s.flowStream(ctx, src, failed); err != nil {
+			return s.failedReport(start, failed, err)
+		}
+		return s.report(start, nil, make([]error, len(s.sinks()))), nil
+	}
+	failed, err := s.flowPipes(ctx)
+	if err != nil {
+		return s.failedReport(start, failed, err)
+	}
+	if err := ctx.Err(); err != nil {
+		return s.failedReport(start, failed, fmt.Errorf("structure (%s) stopped before dumping results, err: %w", s.Description, err))
+	}
+	// without an explicit order sinks follow the order of the source CSV headers
+	var order []string
+	for _, src := range s.sources() {
+		order = append(order, src.OutputColumns()...)
+	}
+	// sinks dump independently of each other, a sink that fails does not prevent the others from dumping
+	errs := make([]error, len(s.sinks()))
+	var de DumpError
+	for i, snk := range s.sinks() {
+		s.markFailed(snk, failed)
+		for _, src := range s.sources() {
+			if passesThrough(src, snk) {
+				snk.Include(src.PassThrough())
+			}
+		}
+		snk.Collect(order...)
+		if err := snk.Dump(); err != nil {
+			errs[i] = fmt.Errorf("sink (%s) failed to dump results, err: %v", snk.Name(), err)
+			de.Errors = append(de.Errors, errs[i])
+		}
+	}
+	if len(de.Errors) != 0 {
+		return s.report(start, failed, errs), &de
+	}
+	return s.report(start, failed, errs), nil
+}
+
+// checkSinkColumns checks that no sink gets columns of the same name from different sources, which the sink would merge
+// into a single column. Sources check the output names of their own pipes already, see source.NewSource
+func (s *Structure) checkSinkColumns() error {
+	for _, snk := range s.sinks() {
+		inSink := map[*pipe.Pipe]bool{}
+		for _, p := range snk.Pipes {
+			inSink[p] = true
+		}
+		from := map[string]*source.Source{}
+		for _, src := range s.sources() {
+			var cols []string
+			for _, b := range src.Bindings() {
+				if inSink[b.Pipe] {
+					cols = append(cols, b.Pipe.GetOutputNames(b.Columns)...)
+				}
+			}
+			if src.PassesThrough() && passesThrough(src, snk) {
+				cols = append(cols, src.Unmapped()...)
+			}
+			for _, c := range cols {
+				if other, ok := from[c]; ok && other != src {
+					return fmt.Errorf("sink (%s) gets column (%s) from both sources (%s) and (%s), set distinct output names", snk.Name(), c, other.Description, src.Description)
+				}
+				from[c] = src
+			}
+		}
+	}
+	return nil
+}
+
+// markFailed makes the given sink omit the output of the pipes it lists that failed, and annotate their columns when
+// OnFailure is FailAnnotate
+func (s *Structure) markFailed(snk *sink.Sink, failed map[*pipe.Pipe]error) {
+	var omitted []*pipe.Pipe
+	var annotated []string
+	var names map[*pipe.Pipe][]string
+	for _, p := range snk.Pipes {
+		if failed[p] == nil {
+			continue
+		}
+		omitted = append(omitted, p)
+		if s.OnFailure == FailAnnotate {
+			if names == nil {
+				names = s.outputNames()
+			}
+			annotated = append(annotated, names[p]...)
 		}
-		// TODO: add inform field on pipe to report progress
 	}
-	s.Sink.Collect()
-	if err := s.Sink.Dump(); err != nil {
-		return "", fmt.Errorf("sink failed to dump results, err: %v", err)
+	snk.Omit(omitted...)
+	snk.Annotate(annotated...)
+}
+
+// passesThrough tells whether the given sink gets the columns the given source passes through, i.e whether the sink
+// lists any pipe of the source that flows row by row. Sinks that only list aggregates of the source do not get them
+func passesThrough(src *source.Source, snk *sink.Sink) bool {
+	bound := map[*pipe.Pipe]bool{}
+	for _, p := range src.OrderedPipes() {
+		bound[p] = true
 	}
-	duration := time.Now().Sub(start)
-	return duration.String(), nil
+	for _, p := range snk.Pipes {
+		if bound[p] && !p.Aggregates() {
+			return true
+		}
+	}
+	return false
+}
+
+// reporter returns the reporter the pipes of the structure that do not already have a reporter report to when Inform
+// is set, the structure Reporter or a bar on stdout when it is not set. Neither the structure nor its pipes are changed
+// so the reporter only applies to the flow at hand
+func (s *Structure) reporter() progress.Reporter {
+	if s.Reporter == nil {
+		return progress.NewBarReporter(os.Stdout, progress.DefaultWidth)
+	}
+	return s.Reporter
+}
+
+// pipes returns all the pipes of the structure, the pipes of the sources first and then the registered ones
+func (s *Structure) pipes() []*pipe.Pipe {
+	var pipes []*pipe.Pipe
+	for _, src := range s.sources() {
+		pipes = append(pipes, src.OrderedPipes()...)
+	}
+	return append(pipes, s.Pipes...)
+}
+
+// flowPipes makes the pipes flow wave after wave, see waves, and returns the error of every pipe that failed or did not
+// flow along with the FlowError of the wave that failed, if any
+func (s *Structure) flowPipes(ctx context.Context) (map[*pipe.Pipe]error, error) {
+	waves, err := s.waves()
+	if err != nil {
+		return nil, err
+	}
+	failed := map[*pipe.Pipe]error{}
+	outs := map[*pipe.Pipe]map[string]*column.Column{}
+	// fail is the error of a flow that fails as a whole, the pipes of the following waves do not flow then
+	var fail error
+	for i, wave := range waves {
+		var flowing []*pipe.Pipe
+		for _, p := range wave {
+			if i == 0 {
+				flowing = append(flowing, p)
+				continue
+			}
+			if up := failedUpstream(p, failed); up != nil {
+				failed[p] = fmt.Errorf("pipe (%s) %w as its upstream pipe (%s) failed", p.Description, errDidNotFlow, up.Description)
+				continue
+			}
+			if fail != nil {
+				failed[p] = fmt.Errorf("pipe (%s) %w as other pipes failed", p.Description, errDidNotFlow)
+				continue
+			}
+			in, err := upstreamInput(p, outs)
+			if err != nil {
+				return failed, err
+			}
+			p.SetTypedInput(in)
+			flowing = append(flowing, p)
+		}
+		errs := s.runEach(flowing, func(_ int, p *pipe.Pipe) error {
+			return p.FlowContext(ctx)
+		})
+		for j, p := range flowing {
+			if errs[j] != nil {
+				failed[p] = errs[j]
+				continue
+			}
+			outs[p] = p.GetTypedOutput()
+		}
+		if s.OnFailure == FailAll && fail == nil {
+			fail = flowError(errs)
+		}
+	}
+	return failed, fail
+}
+
+// errDidNotFlow is wrapped by the errors of the pipes that did not flow, e.g as their upstream pipe failed
+var errDidNotFlow = errors.New("did not flow")
+
+// failedUpstream returns the first upstream pipe of p that failed, nil when none did
+func failedUpstream(p *pipe.Pipe, failed map[*pipe.Pipe]error) *pipe.Pipe {
+	for _, up := range p.GetUpstream() {
+		if failed[up] != nil {
+			return up
+		}
+	}
+	return nil
+}
+
+// runEach calls run for every given pipe in its own goroutine, limited by Concurrency, waits for all of them to finish
+// and returns the error of every pipe at its index, nil for the pipes that succeeded
+func (s *Structure) runEach(pipes []*pipe.Pipe, run func(i int, p *pipe.Pipe) error) []error {
+	limit := s.Concurrency
+	if limit <= 0 || limit > len(pipes) {
+		limit = len(pipes)
+	}
+	sem := make(chan struct{}, limit)
+	// every pipe reports its error at its own index so errors follow the order of the source columns
+	errs := make([]error, len(pipes))
+	var wg sync.WaitGroup
+	for i, p := range pipes {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, p *pipe.Pipe) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := run(i, p); err != nil {
+				errs[i] = pipeError(p, err)
+			}
+		}(i, p)
+	}
+	wg.Wait()
+	return errs
+}
+
+// pipeError names the given pipe in the error it failed with, unless the error is a pipe.Error of the pipe already, e.g
+// when it was stopped, so that the pipe is only named once
+func pipeError(p *pipe.Pipe, err error) error {
+	var pe *pipe.Error
+	if errors.As(err, &pe) && pe.Pipe == p.Description {
+		return err
+	}
+	return fmt.Errorf("pipe (%s) failed to flow, err: %w", p.Description, err)
+}
+
+// flowError aggregates the given errors of pipes into a single FlowError, nil when none of the pipes failed
+func flowError(errs []error) error {
+	var fe FlowError
+	for _, err := range errs {
+		if err != nil {
+			fe.Errors = append(fe.Errors, err)
+		}
+	}
+	if len(fe.Errors) != 0 {
+		return &fe
+	}
+	return nil
 }
@@ -1,18 +1,44 @@
 package structure
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/flaviuvadan/pipe-flow/column"
+	"github.com/flaviuvadan/pipe-flow/pipe"
+	"github.com/flaviuvadan/pipe-flow/progress"
 	"github.com/flaviuvadan/pipe-flow/sink"
 	"github.com/flaviuvadan/pipe-flow/source"
+	"github.com/flaviuvadan/pipe-flow/stats"
 )
 
+// sinkReport returns the report of a sink that wrote the given content to the file fn in the working directory
+func sinkReport(fn string, cols []string, rows int, content string) SinkReport {
+	cwd, _ := os.Getwd()
+	return SinkReport{
+		Name:         fn,
+		Path:         path.Join(cwd, fn),
+		Columns:      cols,
+		Rows:         rows,
+		BytesWritten: int64(len(content)),
+	}
+}
+
 func TestStructure_Register(t *testing.T) {
-	testSource, _ := source.NewSource("test source", "test_file.csv", nil)
-	testSink, _ := sink.NewSink("test_result.csv", nil)
+	testSource, _ := source.NewSource("test source", "test_stream.csv", nil)
+	otherSource, _ := source.NewSource("test source", "test_orders.csv", nil)
+	testSink, _ := sink.NewSink("test_result.csv", []*pipe.Pipe{pipe.NewSingleOpsPipe("a", nil)})
+	otherSink, _ := sink.NewSink("test_result.csv", []*pipe.Pipe{pipe.NewSingleOpsPipe("b", nil)})
+	nilSource, _ := source.NewSource("test source", "test_file.csv", nil)
+	nilSink, _ := sink.NewSink("test_result.csv", nil)
 	s := NewStructure("test")
 	tests := []struct {
 		name        string
@@ -29,6 +55,31 @@ func TestStructure_Register(t *testing.T) {
 			toRegister:  testSink,
 			expectedErr: nil,
 		},
+		{
+			name:        "test_rejects_source_of_same_name",
+			toRegister:  otherSource,
+			expectedErr: fmt.Errorf("a source named (test source) is already registered"),
+		},
+		{
+			name:        "test_rejects_sink_of_same_name",
+			toRegister:  otherSink,
+			expectedErr: fmt.Errorf("a sink named (test_result.csv) is already registered"),
+		},
+		{
+			name:        "test_rejects_nil_source",
+			toRegister:  nilSource,
+			expectedErr: fmt.Errorf("cannot register a nil Source"),
+		},
+		{
+			name:        "test_rejects_nil_sink",
+			toRegister:  nilSink,
+			expectedErr: fmt.Errorf("cannot register a nil Sink"),
+		},
+		{
+			name:        "test_rejects_nil_sink_again",
+			toRegister:  nilSink,
+			expectedErr: fmt.Errorf("cannot register a nil Sink"),
+		},
 		{
 			name:        "test_falls_on_default_error",
 			toRegister:  "",
@@ -38,9 +89,778 @@ func TestStructure_Register(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			err := s.Register(tt.toRegister)
-			if err != nil {
+			if tt.expectedErr != nil {
 				assert.EqualError(t, err, tt.expectedErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+	assert.Equal(t, []*source.Source{testSource}, s.Sources)
+	assert.Equal(t, []*sink.Sink{testSink}, s.Sinks)
+}
+
+func TestStructure_Flow_NilSourceAndSink(t *testing.T) {
+	src, err := source.NewSource("test", "test_stream.csv", map[string]*pipe.Pipe{"a": addPipe("a", "a", 1)})
+	assert.NoError(t, err)
+	snk, _ := sink.NewSink("test_nil.csv", src.OrderedPipes())
+
+	s := NewStructure("test")
+	s.Sources = []*source.Source{nil}
+	s.Sinks = []*sink.Sink{snk}
+	_, err = s.Flow()
+	assert.EqualError(t, err, "cannot flow with nil Source")
+
+	s = NewStructure("test")
+	s.Sources = []*source.Source{src}
+	s.Sinks = []*sink.Sink{nil}
+	_, err = s.Flow()
+	assert.EqualError(t, err, "cannot flow with nil Sink")
+}
+
+func TestStructure_flowPipes(t *testing.T) {
+	okOps := []func(float64) (float64, error){
+		func(v float64) (float64, error) {
+			return v + 1, nil
+		},
+	}
+	badOps := []func(float64) (float64, error){
+		func(v float64) (float64, error) {
+			return 0, fmt.Errorf("failed")
+		},
+	}
+	tests := []struct {
+		name           string
+		concurrency    int
+		pipes          map[string]*pipe.Pipe
+		expectedErrors int
+	}{
+		{
+			name:        "test_flows_all_pipes_without_limit",
+			concurrency: 0,
+			pipes: map[string]*pipe.Pipe{
+				"a": pipe.NewSingleOpsPipe("a_pipe", okOps),
+				"b": pipe.NewSingleOpsPipe("b_pipe", okOps),
+			},
+			expectedErrors: 0,
+		},
+		{
+			name:        "test_flows_all_pipes_with_limit",
+			concurrency: 1,
+			pipes: map[string]*pipe.Pipe{
+				"a": pipe.NewSingleOpsPipe("a_pipe", okOps),
+				"b": pipe.NewSingleOpsPipe("b_pipe", okOps),
+				"c": pipe.NewSingleOpsPipe("c_pipe", okOps),
+			},
+			expectedErrors: 0,
+		},
+		{
+			name:        "test_collects_errors_of_all_failing_pipes",
+			concurrency: 2,
+			pipes: map[string]*pipe.Pipe{
+				"a": pipe.NewSingleOpsPipe("a_pipe", badOps),
+				"b": pipe.NewSingleOpsPipe("b_pipe", okOps),
+				"c": pipe.NewSingleOpsPipe("c_pipe", badOps),
+			},
+			expectedErrors: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, p := range tt.pipes {
+				p.SetInput(map[string][]float64{k: {1, 2, 3}})
 			}
+			s := NewStructure(tt.name)
+			s.Concurrency = tt.concurrency
+			s.Sources = []*source.Source{{Pipes: tt.pipes}}
+			_, err := s.flowPipes(context.Background())
+			if tt.expectedErrors == 0 {
+				assert.NoError(t, err)
+				for k, p := range tt.pipes {
+					assert.Equal(t, []float64{2, 3, 4}, p.GetOutput()[k])
+				}
+				return
+			}
+			fe, ok := err.(*FlowError)
+			assert.True(t, ok)
+			assert.Len(t, fe.Errors, tt.expectedErrors)
+			assert.Contains(t, fe.Errors[0].Error(), "pipe (a_pipe) failed to flow")
+			assert.Contains(t, fe.Errors[1].Error(), "pipe (c_pipe) failed to flow")
+		})
+	}
+}
+
+func TestStructure_FlowContext(t *testing.T) {
+	p := pipe.NewSingleOpsPipeContext("a_pipe", []pipe.SingleOpContext{
+		func(ctx context.Context, v float64) (float64, error) {
+			<-ctx.Done()
+			return 0, ctx.Err()
+		},
+	})
+	p.SetInput(map[string][]float64{"a": {1, 2, 3}})
+	snk, _ := sink.NewSink("test_flow_context_result.csv", []*pipe.Pipe{p})
+	s := NewStructure("test")
+	s.Sources = []*source.Source{{Pipes: map[string]*pipe.Pipe{"a": p}}}
+	s.Sinks = []*sink.Sink{snk}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	res, err := s.FlowContext(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	// the pipe is named once even though it names itself when stopped
+	assert.EqualError(t, err, "1 pipe/s failed to flow: pipe (a_pipe) stopped, err: context deadline exceeded")
+	assert.Len(t, res.Failed(), 1)
+	assert.True(t, errors.Is(res.Failed()[0].Err, context.DeadlineExceeded))
+	assert.EqualError(t, res.Sinks[0].Err, "sink (test_flow_context_result.csv) did not dump as the flow failed")
+	_, statErr := os.Stat("test_flow_context_result.csv")
+	assert.True(t, os.IsNotExist(statErr))
+}
+
+func TestStructure_Flow_Inform(t *testing.T) {
+	for _, chunkSize := range []int{0, 2} {
+		t.Run(fmt.Sprintf("test_reports_to_structure_reporter_with_chunk_size_%d", chunkSize), func(t *testing.T) {
+			own := progress.NewChanReporter(10)
+			double := []func(float64) (float64, error){
+				func(v float64) (float64, error) {
+					return v * 2, nil
+				},
+			}
+			a := pipe.NewSingleOpsPipe("a_pipe", double)
+			b := pipe.NewSingleOpsPipe("b_pipe", double)
+			b.SetReporter(own, 1)
+			src, err := source.NewSource("test", "test_stream.csv", map[string]*pipe.Pipe{"a": a, "b": b},
+				source.WithChunkSize(chunkSize))
+			assert.NoError(t, err)
+			fn := fmt.Sprintf("test_inform_%d.csv", chunkSize)
+			snk, _ := sink.NewSink(fn, []*pipe.Pipe{a, b})
+			shared := progress.NewChanReporter(10)
+			s := NewStructure("test")
+			s.Inform = true
+			s.Reporter = shared
+			_ = s.Register(src)
+			_ = s.Register(snk)
+			_, err = s.Flow()
+			assert.NoError(t, err)
+			shared.Close()
+			own.Close()
+			toShared, toOwn := map[string]bool{}, map[string]bool{}
+			for u := range shared.Updates() {
+				toShared[u.Description] = true
+			}
+			for u := range own.Updates() {
+				toOwn[u.Description] = true
+			}
+			assert.Equal(t, map[string]bool{"a_pipe": true}, toShared)
+			assert.Equal(t, map[string]bool{"b_pipe": true}, toOwn)
+			// the reporter only applies to the flow, neither the structure nor its pipes are changed
+			assert.Nil(t, a.GetReporter())
+			assert.Equal(t, own, b.GetReporter())
+			assert.Equal(t, shared, s.Reporter)
+			if err := os.Remove(fn); err != nil {
+				panic(fmt.Errorf("could not remove %v for tests teardown", fn))
+			}
+		})
+	}
+}
+
+func TestStructure_Flow_PassThrough(t *testing.T) {
+	for _, chunkSize := range []int{0, 2} {
+		t.Run(fmt.Sprintf("test_passes_unmapped_columns_to_sink_with_chunk_size_%d", chunkSize), func(t *testing.T) {
+			a := pipe.NewSingleOpsPipe("a_pipe", []func(float64) (float64, error){
+				func(v float64) (float64, error) {
+					return v * 2, nil
+				},
+			})
+			src, err := source.NewSource("test", "test_stream.csv", map[string]*pipe.Pipe{"a": a},
+				source.WithChunkSize(chunkSize), source.WithUnmapped(source.PassThrough))
+			assert.NoError(t, err)
+			fn := fmt.Sprintf("test_pass_through_%d.csv", chunkSize)
+			snk, _ := sink.NewSink(fn, []*pipe.Pipe{a})
+			s := NewStructure("test")
+			_ = s.Register(src)
+			_ = s.Register(snk)
+			_, err = s.Flow()
+			assert.NoError(t, err)
+			content, err := ioutil.ReadFile(fn)
+			assert.NoError(t, err)
+			assert.Equal(t, "a,b\n2.000,10\n4.000,20\n6.000,30\n", string(content))
+			if err := os.Remove(fn); err != nil {
+				panic(fmt.Errorf("could not remove %v for tests teardown", fn))
+			}
+		})
+	}
+}
+
+func TestStructure_Flow_FanOut(t *testing.T) {
+	for _, chunkSize := range []int{0, 2} {
+		t.Run(fmt.Sprintf("test_flows_column_through_many_pipes_with_chunk_size_%d", chunkSize), func(t *testing.T) {
+			plusOne := pipe.NewSingleOpsPipe("a_plus1", []func(float64) (float64, error){
+				func(v float64) (float64, error) {
+					return v + 1, nil
+				},
+			})
+			plusOne.SetOutputName("a_plus1")
+			sum := pipe.NewAccumulatorPipe("a_sum", func() pipe.Accumulator {
+				return &sumAccumulator{}
+			})
+			sum.SetOutputName("a_sum")
+			a := pipe.NewSingleOpsPipe("a", []func(float64) (float64, error){
+				func(v float64) (float64, error) {
+					return v, nil
+				},
+			})
+			src, err := source.NewSource("test", "test_stream.csv", map[string]*pipe.Pipe{"a": a},
+				source.WithChunkSize(chunkSize), source.WithPipes("a", sum, plusOne))
+			assert.NoError(t, err)
+			fn := fmt.Sprintf("test_fan_out_%d.csv", chunkSize)
+			snk, _ := sink.NewSink(fn, []*pipe.Pipe{plusOne, sum, a})
+			s := NewStructure("test")
+			_ = s.Register(src)
+			_ = s.Register(snk)
+			_, err = s.Flow()
+			assert.NoError(t, err)
+			content, err := ioutil.ReadFile(fn)
+			assert.NoError(t, err)
+			expected := "a,a_sum,a_plus1\n1.000,6.000,2.000\n2.000,,3.000\n3.000,,4.000\n"
+			if chunkSize > 0 {
+				expected = "a,a_sum,a_plus1\n1.000,,2.000\n2.000,,3.000\n3.000,,4.000\n,6.000,\n"
+			}
+			assert.Equal(t, expected, string(content))
+			if err := os.Remove(fn); err != nil {
+				panic(fmt.Errorf("could not remove %v for tests teardown", fn))
+			}
+		})
+	}
+}
+
+func TestStructure_Flow_RowPipes(t *testing.T) {
+	for _, chunkSize := range []int{0, 2} {
+		t.Run(fmt.Sprintf("test_flows_records_through_row_pipes_with_chunk_size_%d", chunkSize), func(t *testing.T) {
+			revenue := pipe.NewRowPipe("revenue", []string{"price", "qty"}, map[string]column.Type{"revenue": column.Float},
+				func(_ context.Context, r pipe.Record) (pipe.Record, error) {
+					price, err := r.Float("price")
+					if err != nil {
+						return nil, err
+					}
+					qty, err := r.Float("qty")
+					if err != nil {
+						return nil, err
+					}
+					return pipe.Record{"revenue": price * qty}, nil
+				})
+			src, err := source.NewSource("test", "test_orders.csv", nil, source.WithChunkSize(chunkSize),
+				source.WithRowPipes(revenue), source.WithUnmapped(source.PassThrough))
+			assert.NoError(t, err)
+			fn := fmt.Sprintf("test_row_pipes_%d.csv", chunkSize)
+			snk, _ := sink.NewSink(fn, []*pipe.Pipe{revenue})
+			s := NewStructure("test")
+			_ = s.Register(src)
+			_ = s.Register(snk)
+			_, err = s.Flow()
+			assert.NoError(t, err)
+			content, err := ioutil.ReadFile(fn)
+			assert.NoError(t, err)
+			assert.Equal(t, "item,revenue\npen,3.000\nbook,12.000\nink,17.000\n", string(content))
+			if err := os.Remove(fn); err != nil {
+				panic(fmt.Errorf("could not remove %v for tests teardown", fn))
+			}
+		})
+	}
+}
+
+func TestStructure_Flow_MultipleSourcesAndSinks(t *testing.T) {
+	sum := func(vs []float64) (float64, error) {
+		total := 0.0
+		for _, v := range vs {
+			total += v
+		}
+		return total, nil
+	}
+	salesTotal := pipe.NewAggregateOpPipe("sales_total", sum)
+	costsTotal := pipe.NewAggregateOpPipe("costs_total", sum)
+	costs := pipe.NewSingleOpsPipe("costs", []func(float64) (float64, error){
+		func(v float64) (float64, error) {
+			return v * 2, nil
+		},
+	})
+	costs.SetOutputName("double_cost")
+	sales, err := source.NewSource("sales", "test_sales.csv", map[string]*pipe.Pipe{"sales": salesTotal})
+	assert.NoError(t, err)
+	costsSrc, err := source.NewSource("costs", "test_costs.csv", map[string]*pipe.Pipe{"cost": costsTotal},
+		source.WithPipes("cost", costs), source.WithUnmapped(source.PassThrough))
+	assert.NoError(t, err)
+	summary, _ := sink.NewSink("test_summary.csv", []*pipe.Pipe{salesTotal, costsTotal})
+	detail, _ := sink.NewSink("test_detail.csv", []*pipe.Pipe{costs})
+
+	s := NewStructure("test")
+	for _, r := range []interface{}{sales, costsSrc, summary, detail} {
+		assert.NoError(t, s.Register(r))
+	}
+	res, err := s.Flow()
+	assert.NoError(t, err)
+	summaryContent := "sales,cost\n30.000,10.000\n"
+	detailContent := "item,double_cost\npen,3.000\nink,5.000\nbook,12.000\n"
+	assert.Equal(t, []SinkReport{
+		sinkReport("test_summary.csv", []string{"sales", "cost"}, 1, summaryContent),
+		sinkReport("test_detail.csv", []string{"item", "double_cost"}, 3, detailContent),
+	}, res.Sinks)
+	for fn, expected := range map[string]string{
+		"test_summary.csv": summaryContent,
+		"test_detail.csv":  detailContent,
+	} {
+		content, err := ioutil.ReadFile(fn)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(content))
+		if err := os.Remove(fn); err != nil {
+			panic(fmt.Errorf("could not remove %v for tests teardown", fn))
+		}
+	}
+}
+
+func TestStructure_Flow_StreamsIntoMultipleSinks(t *testing.T) {
+	a := addPipe("a", "a", 1)
+	b := pipe.NewAccumulatorPipe("b", func() pipe.Accumulator {
+		return &sumAccumulator{}
+	})
+	src, err := source.NewSource("test", "test_stream.csv", map[string]*pipe.Pipe{"a": a, "b": b}, source.WithChunkSize(2))
+	assert.NoError(t, err)
+	first, _ := sink.NewSink("test_stream_first.csv", []*pipe.Pipe{a})
+	second, _ := sink.NewSink("test_stream_second.csv", []*pipe.Pipe{b})
+	s := NewStructure("test")
+	for _, r := range []interface{}{src, first, second} {
+		assert.NoError(t, s.Register(r))
+	}
+	res, err := s.Flow()
+	assert.NoError(t, err)
+	firstContent := "a\n2.000\n3.000\n4.000\n"
+	secondContent := "b\n60.000\n"
+	assert.Equal(t, []SinkReport{
+		sinkReport("test_stream_first.csv", []string{"a"}, 3, firstContent),
+		sinkReport("test_stream_second.csv", []string{"b"}, 1, secondContent),
+	}, res.Sinks)
+	for fn, expected := range map[string]string{
+		"test_stream_first.csv":  firstContent,
+		"test_stream_second.csv": secondContent,
+	} {
+		content, err := ioutil.ReadFile(fn)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(content))
+		if err := os.Remove(fn); err != nil {
+			panic(fmt.Errorf("could not remove %v for tests teardown", fn))
+		}
+	}
+
+	other, _ := source.NewSource("other", "test_sales.csv", nil)
+	assert.NoError(t, s.Register(other))
+	_, err = s.Flow()
+	assert.EqualError(t, err, "streaming source (test) cannot flow along with other sources")
+}
+
+func TestStructure_Flow_Filters(t *testing.T) {
+	for _, chunkSize := range []int{0, 2} {
+		t.Run(fmt.Sprintf("test_drops_filtered_rows_from_all_columns_with_chunk_size_%d", chunkSize), func(t *testing.T) {
+			a := pipe.NewFilterPipe("a_filter", func(v float64) (bool, error) {
+				return v != 2, nil
+			})
+			src, err := source.NewSource("test", "test_stream.csv", map[string]*pipe.Pipe{"a": a},
+				source.WithChunkSize(chunkSize), source.WithUnmapped(source.PassThrough))
+			assert.NoError(t, err)
+			fn := fmt.Sprintf("test_filters_%d.csv", chunkSize)
+			snk, _ := sink.NewSink(fn, []*pipe.Pipe{a})
+			s := NewStructure("test")
+			_ = s.Register(src)
+			_ = s.Register(snk)
+			_, err = s.Flow()
+			assert.NoError(t, err)
+			assert.Equal(t, 1, a.GetDropped())
+			content, err := ioutil.ReadFile(fn)
+			assert.NoError(t, err)
+			assert.Equal(t, "a,b\n1.000,10\n3.000,30\n", string(content))
+			if err := os.Remove(fn); err != nil {
+				panic(fmt.Errorf("could not remove %v for tests teardown", fn))
+			}
+		})
+	}
+}
+
+func TestStructure_Flow_Windows(t *testing.T) {
+	for _, chunkSize := range []int{0, 2} {
+		t.Run(fmt.Sprintf("test_flows_rolling_windows_with_chunk_size_%d", chunkSize), func(t *testing.T) {
+			a := pipe.NewRollingPipe("a_rolling", 2, stats.Sum)
+			src, err := source.NewSource("test", "test_stream.csv", map[string]*pipe.Pipe{"a": a},
+				source.WithChunkSize(chunkSize), source.WithUnmapped(source.PassThrough))
+			assert.NoError(t, err)
+			fn := fmt.Sprintf("test_windows_%d.csv", chunkSize)
+			snk, _ := sink.NewSink(fn, []*pipe.Pipe{a})
+			s := NewStructure("test")
+			_ = s.Register(src)
+			_ = s.Register(snk)
+			_, err = s.Flow()
+			assert.NoError(t, err)
+			content, err := ioutil.ReadFile(fn)
+			assert.NoError(t, err)
+			assert.Equal(t, "a,b\n,10\n3.000,20\n5.000,30\n", string(content))
+			if err := os.Remove(fn); err != nil {
+				panic(fmt.Errorf("could not remove %v for tests teardown", fn))
+			}
+		})
+	}
+}
+
+func TestStructure_Flow_FailAllReport(t *testing.T) {
+	a := addPipe("a", "a", 1)
+	b := pipe.NewSingleOpsPipe("b", []func(float64) (float64, error){
+		func(v float64) (float64, error) {
+			return 0, fmt.Errorf("failed")
+		},
+	})
+	bPlusOne := addPipe("b_plus1", "b_plus1", 1)
+	bPlusOne.SetUpstream(b)
+	aPlusOne := addPipe("a_plus1", "a_plus1", 1)
+	aPlusOne.SetUpstream(a)
+	src, err := source.NewSource("test", "test_stream.csv", map[string]*pipe.Pipe{"a": a, "b": b})
+	assert.NoError(t, err)
+	fn := "test_fail_all_report.csv"
+	snk, _ := sink.NewSink(fn, []*pipe.Pipe{a, b, aPlusOne, bPlusOne})
+	s := NewStructure("test")
+	for _, r := range []interface{}{src, aPlusOne, bPlusOne, snk} {
+		assert.NoError(t, s.Register(r))
+	}
+	res, err := s.Flow()
+	assert.EqualError(t, err, "1 pipe/s failed to flow: pipe (b) failed to flow, err: failed to apply op to val 10 on row 0 with op msg: failed")
+	succeeded := res.Succeeded()
+	assert.Len(t, succeeded, 1)
+	assert.Equal(t, "a", succeeded[0].Name)
+	assert.Equal(t, 3, succeeded[0].RowsOut)
+	failed := map[string]PipeReport{}
+	for _, p := range res.Failed() {
+		failed[p.Name] = p
+	}
+	assert.Len(t, failed, 3)
+	assert.EqualError(t, failed["b"].Err, "pipe (b) failed to flow, err: failed to apply op to val 10 on row 0 with op msg: failed")
+	assert.Equal(t, 1, failed["b"].Attempts)
+	assert.EqualError(t, failed["b_plus1"].Err, "pipe (b_plus1) did not flow as its upstream pipe (b) failed")
+	assert.EqualError(t, failed["a_plus1"].Err, "pipe (a_plus1) did not flow as other pipes failed")
+	assert.Equal(t, 0, failed["a_plus1"].Attempts)
+	assert.EqualError(t, res.Sinks[0].Err, "sink (test_fail_all_report.csv) did not dump as the flow failed")
+	_, statErr := os.Stat(fn)
+	assert.True(t, os.IsNotExist(statErr))
+}
+
+func TestStructure_Flow_TumblingWindowsStreamLikeWholeSources(t *testing.T) {
+	expected := "a,b\n3.000,30.000\n3.000,50.000\n"
+	for _, chunkSize := range []int{0, 1, 2} {
+		t.Run(fmt.Sprintf("test_flows_tumbling_windows_with_chunk_size_%d", chunkSize), func(t *testing.T) {
+			a := pipe.NewTumblingPipe("a_tumbling", 2, stats.Sum)
+			b := pipe.NewSlidingPipe("b_sliding", 2, 1, stats.Sum)
+			src, err := source.NewSource("test", "test_stream.csv", map[string]*pipe.Pipe{"a": a, "b": b},
+				source.WithChunkSize(chunkSize))
+			assert.NoError(t, err)
+			fn := fmt.Sprintf("test_tumbling_windows_%d.csv", chunkSize)
+			snk, _ := sink.NewSink(fn, []*pipe.Pipe{a, b})
+			s := NewStructure("test")
+			_ = s.Register(src)
+			_ = s.Register(snk)
+			_, err = s.Flow()
+			assert.NoError(t, err)
+			content, err := ioutil.ReadFile(fn)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, string(content))
+			if err := os.Remove(fn); err != nil {
+				panic(fmt.Errorf("could not remove %v for tests teardown", fn))
+			}
+		})
+	}
+}
+
+func TestStructure_Flow_GroupBy(t *testing.T) {
+	totals := pipe.NewGroupByPipe("totals", "region", "amount", stats.Sum)
+	totals.SetOutputName("total")
+	src, err := source.NewSource("test", "test_regions.csv", nil, source.WithRowPipes(totals),
+		source.WithNullPolicy("amount", source.NullPolicy{Action: source.NullMark}))
+	assert.NoError(t, err)
+	fn := "test_group_by.csv"
+	snk, _ := sink.NewSink(fn, []*pipe.Pipe{totals})
+	s := NewStructure("test")
+	_ = s.Register(src)
+	_ = s.Register(snk)
+	_, err = s.Flow()
+	assert.NoError(t, err)
+	content, err := ioutil.ReadFile(fn)
+	assert.NoError(t, err)
+	assert.Equal(t, "region,total\nnorth,12.500\nsouth,6.000\neast,\n", string(content))
+	if err := os.Remove(fn); err != nil {
+		panic(fmt.Errorf("could not remove %v for tests teardown", fn))
+	}
+}
+
+func TestStructure_Flow_MultiAggregatesAndBroadcast(t *testing.T) {
+	stats := pipe.NewMultiAggregatePipe("a_stats", pipe.Aggregate{Name: "sum", Op: stats.Sum}, pipe.Aggregate{Name: "mean", Op: stats.Mean})
+	share := pipe.NewPercentOfTotalPipe("b_share")
+	src, err := source.NewSource("test", "test_stream.csv", map[string]*pipe.Pipe{"a": stats, "b": share})
+	assert.NoError(t, err)
+	fn := "test_multi_aggregates.csv"
+	snk, _ := sink.NewSink(fn, []*pipe.Pipe{stats, share})
+	s := NewStructure("test")
+	_ = s.Register(src)
+	_ = s.Register(snk)
+	_, err = s.Flow()
+	assert.NoError(t, err)
+	content, err := ioutil.ReadFile(fn)
+	assert.NoError(t, err)
+	assert.Equal(t, "a_sum,a_mean,b\n6.000,2.000,16.667\n,,33.333\n,,50.000\n", string(content))
+	if err := os.Remove(fn); err != nil {
+		panic(fmt.Errorf("could not remove %v for tests teardown", fn))
+	}
+}
+
+func TestStructure_Flow_ErrorPolicy(t *testing.T) {
+	for _, chunkSize := range []int{0, 2} {
+		t.Run(fmt.Sprintf("test_skips_failed_rows_in_all_columns_with_chunk_size_%d", chunkSize), func(t *testing.T) {
+			a := pipe.NewSingleOpsPipe("a_pipe", []func(float64) (float64, error){
+				func(v float64) (float64, error) {
+					if v == 2 {
+						return 0, fmt.Errorf("test error")
+					}
+					return v, nil
+				},
+			})
+			a.SetErrorPolicy(pipe.ErrorPolicy{Action: pipe.ErrorSkip})
+			src, err := source.NewSource("test", "test_stream.csv", map[string]*pipe.Pipe{"a": a},
+				source.WithChunkSize(chunkSize), source.WithUnmapped(source.PassThrough))
+			assert.NoError(t, err)
+			fn := fmt.Sprintf("test_error_policy_%d.csv", chunkSize)
+			snk, _ := sink.NewSink(fn, []*pipe.Pipe{a})
+			s := NewStructure("test")
+			_ = s.Register(src)
+			_ = s.Register(snk)
+			_, err = s.Flow()
+			assert.NoError(t, err)
+			assert.Equal(t, 1, a.GetErrors())
+			content, err := ioutil.ReadFile(fn)
+			assert.NoError(t, err)
+			assert.Equal(t, "a,b\n1.000,10\n3.000,30\n", string(content))
+			if err := os.Remove(fn); err != nil {
+				panic(fmt.Errorf("could not remove %v for tests teardown", fn))
+			}
+		})
+	}
+}
+
+func TestStructure_Flow_PartialFailures(t *testing.T) {
+	tests := []struct {
+		name      string
+		onFailure FailureMode
+		expected  string
+	}{
+		{
+			name:      "test_omits_columns_of_failed_pipes",
+			onFailure: FailOmit,
+			expected:  "a\n2.000\n3.000\n4.000\n",
+		},
+		{
+			name:      "test_annotates_columns_of_failed_pipes",
+			onFailure: FailAnnotate,
+			expected:  "a,b,b_plus1\n2.000,#FAILED,#FAILED\n3.000,#FAILED,#FAILED\n4.000,#FAILED,#FAILED\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := addPipe("a", "a", 1)
+			b := pipe.NewSingleOpsPipe("b", []func(float64) (float64, error){
+				func(v float64) (float64, error) {
+					return 0, fmt.Errorf("failed")
+				},
+			})
+			plusOne := addPipe("b_plus1", "b_plus1", 1)
+			plusOne.SetUpstream(b)
+			src, err := source.NewSource("test", "test_stream.csv", map[string]*pipe.Pipe{"a": a, "b": b})
+			assert.NoError(t, err)
+			fn := fmt.Sprintf("%s.csv", tt.name)
+			snk, _ := sink.NewSink(fn, []*pipe.Pipe{a, b, plusOne})
+			s := NewStructure("test")
+			s.OnFailure = tt.onFailure
+			for _, r := range []interface{}{src, plusOne, snk} {
+				assert.NoError(t, s.Register(r))
+			}
+			res, err := s.Flow()
+			assert.NoError(t, err)
+			succeeded := res.Succeeded()
+			assert.Len(t, succeeded, 1)
+			assert.Equal(t, "a", succeeded[0].Name)
+			failed := res.Failed()
+			assert.Len(t, failed, 2)
+			assert.Equal(t, "b", failed[0].Name)
+			assert.EqualError(t, failed[0].Err, "pipe (b) failed to flow, err: failed to apply op to val 10 on row 0 with op msg: failed")
+			assert.Equal(t, 1, failed[0].Attempts)
+			assert.Equal(t, "b_plus1", failed[1].Name)
+			assert.EqualError(t, failed[1].Err, "pipe (b_plus1) did not flow as its upstream pipe (b) failed")
+			assert.Equal(t, 0, failed[1].Attempts)
+			content, err := ioutil.ReadFile(fn)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, string(content))
+			if err := os.Remove(fn); err != nil {
+				panic(fmt.Errorf("could not remove %v for tests teardown", fn))
+			}
+		})
+	}
+
+	src, err := source.NewSource("test", "test_stream.csv", map[string]*pipe.Pipe{"a": addPipe("a", "a", 1)}, source.WithChunkSize(2))
+	assert.NoError(t, err)
+	snk, _ := sink.NewSink("test_partial_stream.csv", src.OrderedPipes())
+	s := NewStructure("test")
+	s.OnFailure = FailOmit
+	_ = s.Register(src)
+	_ = s.Register(snk)
+	_, err = s.Flow()
+	assert.EqualError(t, err, "streaming source (test) cannot flow with partial failures")
+}
+
+func TestStructure_Flow_DeprecatedSourceAndSink(t *testing.T) {
+	a := pipe.NewSingleOpsPipe("a_pipe", []func(float64) (float64, error){
+		func(v float64) (float64, error) {
+			return v * 2, nil
+		},
+	})
+	src, err := source.NewSource("test", "test_stream.csv", map[string]*pipe.Pipe{"a": a})
+	assert.NoError(t, err)
+	fn := "test_deprecated.csv"
+	snk, _ := sink.NewSink(fn, []*pipe.Pipe{a})
+	s := NewStructure("test")
+	s.Source = src
+	s.Sink = snk
+	r, err := s.Flow()
+	assert.NoError(t, err)
+	assert.Equal(t, r.Duration.String(), fmt.Sprintf("%v", r))
+	content, err := ioutil.ReadFile(fn)
+	assert.NoError(t, err)
+	assert.Equal(t, "a\n2.000\n4.000\n6.000\n", string(content))
+	if err := os.Remove(fn); err != nil {
+		panic(fmt.Errorf("could not remove %v for tests teardown", fn))
+	}
+
+	// registering sets the deprecated fields as well, without flowing them twice
+	s = NewStructure("test")
+	assert.NoError(t, s.Register(src))
+	assert.NoError(t, s.Register(snk))
+	assert.Equal(t, src, s.Source)
+	assert.Equal(t, snk, s.Sink)
+	assert.Len(t, s.sources(), 1)
+	assert.Len(t, s.sinks(), 1)
+}
+
+func TestStructure_Flow_ReusedSinkFollowsSourceOrder(t *testing.T) {
+	a := addPipe("a", "a", 1)
+	b := addPipe("b", "b", 1)
+	fn := "test_reused_sink.csv"
+	snk, _ := sink.NewSink(fn, []*pipe.Pipe{a, b})
+	for file, expected := range map[string]string{
+		"test_stream.csv":  "a,b\n2.000,11.000\n3.000,21.000\n4.000,31.000\n",
+		"test_swapped.csv": "b,a\n11.000,2.000\n21.000,3.000\n",
+	} {
+		src, err := source.NewSource("test", file, map[string]*pipe.Pipe{"a": a, "b": b})
+		assert.NoError(t, err)
+		s := NewStructure("test")
+		_ = s.Register(src)
+		_ = s.Register(snk)
+		_, err = s.Flow()
+		assert.NoError(t, err)
+		// the order of the source header is not kept on the sink
+		assert.Nil(t, snk.Columns())
+		content, err := ioutil.ReadFile(fn)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(content))
+	}
+	if err := os.Remove(fn); err != nil {
+		panic(fmt.Errorf("could not remove %v for tests teardown", fn))
+	}
+}
+
+func TestStructure_Flow_DuplicateColumnsAcrossSources(t *testing.T) {
+	tests := []struct {
+		name        string
+		swappedOut  string
+		passThrough bool
+		expectedErr error
+	}{
+		{
+			name:        "test_same_output_name",
+			swappedOut:  "a",
+			expectedErr: fmt.Errorf("sink (test_duplicates.csv) gets column (a) from both sources (stream) and (swapped), set distinct output names"),
+		},
+		{
+			name:        "test_pass_through_column",
+			swappedOut:  "swapped_a",
+			passThrough: true,
+			expectedErr: fmt.Errorf("sink (test_duplicates.csv) gets column (b) from both sources (stream) and (swapped), set distinct output names"),
+		},
+		{
+			name:       "test_distinct_output_names",
+			swappedOut: "swapped_a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn := "test_duplicates.csv"
+			a := addPipe("a", "a", 1)
+			b := addPipe("b", "b", 1)
+			swapped := addPipe("swapped", tt.swappedOut, 1)
+			stream, err := source.NewSource("stream", "test_stream.csv", map[string]*pipe.Pipe{"a": a, "b": b})
+			assert.NoError(t, err)
+			var opts []source.Option
+			if tt.passThrough {
+				opts = append(opts, source.WithUnmapped(source.PassThrough))
+			}
+			swappedSrc, err := source.NewSource("swapped", "test_swapped.csv", map[string]*pipe.Pipe{"a": swapped}, opts...)
+			assert.NoError(t, err)
+			snk, _ := sink.NewSink(fn, []*pipe.Pipe{a, b, swapped})
+
+			s := NewStructure("test")
+			for _, r := range []interface{}{stream, swappedSrc, snk} {
+				assert.NoError(t, s.Register(r))
+			}
+			_, err = s.Flow()
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			if err := os.Remove(fn); err != nil {
+				panic(fmt.Errorf("could not remove %v for tests teardown", fn))
+			}
+		})
+	}
+}
+
+func TestStructure_pipeError(t *testing.T) {
+	p := pipe.NewSingleOpsPipe("a", nil)
+	p.SetOutputName("out")
+	p.SetInput(map[string][]float64{"a": {1}, "b": {2}})
+	named := p.Flow()
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			name:     "test_names_the_pipe",
+			err:      fmt.Errorf("failed"),
+			expected: "pipe (a) failed to flow, err: failed",
+		},
+		{
+			name:     "test_does_not_name_the_pipe_twice",
+			err:      named,
+			expected: "pipe (a) outputs column (out) more than once, output names need a single input column",
+		},
+		{
+			name:     "test_names_the_pipe_of_op_errors_that_look_named",
+			err:      fmt.Errorf("pipe (a) is down"),
+			expected: "pipe (a) failed to flow, err: pipe (a) is down",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.EqualError(t, pipeError(p, tt.err), tt.expected)
 		})
 	}
 }